@@ -1,5 +1,11 @@
 package checks
 
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
 // HealthcheckExpectations defines expectations for a healthcheck
 type HealthcheckExpectations struct {
 	Expectations []interface{}
@@ -81,6 +87,74 @@ func (ag *AssertionGroup) AssertTrue(name string, condition bool, expected inter
 	ag.Result = result
 }
 
+// FailedAssertion defines a failed assertion found in the output of a healthcheck
+type FailedAssertion struct {
+	Group    string `json:"group"`
+	Type     string `json:"type"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// FailedAssertions collects the failed assertions of all assertion groups in the output, including nested results
+func FailedAssertions(output interface{}) []FailedAssertion {
+	var generic interface{}
+	if data, err := json.Marshal(output); err != nil || json.Unmarshal(data, &generic) != nil {
+		return nil
+	}
+
+	found := make([]FailedAssertion, 0)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			if assertions, ok := value["assertions"].([]interface{}); ok {
+				group, _ := value["name"].(string)
+				for _, a := range assertions {
+					assertion, ok := a.(map[string]interface{})
+					if !ok || assertion["result"] != Failed {
+						continue
+					}
+					t, _ := assertion["type"].(string)
+					found = append(found, FailedAssertion{
+						Group:    group,
+						Type:     t,
+						Expected: formatValue(assertion["expected"]),
+						Actual:   formatValue(assertion["actual"]),
+					})
+				}
+			}
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if key != "assertions" {
+					walk(value[key])
+				}
+			}
+		case []interface{}:
+			for _, item := range value {
+				walk(item)
+			}
+		}
+	}
+	walk(generic)
+
+	return found
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
 func contains(s []string, e string) bool {
 	for _, a := range s {
 		if a == e {
@@ -1,6 +1,7 @@
 package checks
 
 import (
+	"context"
 	"reflect"
 )
 
@@ -10,6 +11,9 @@ const Failed string = "failed"
 // Passed defines a passed check
 const Passed string = "passed"
 
+// Disabled defines a check that has been disabled and was not executed
+const Disabled string = "disabled"
+
 // Result defines a healthcheck result
 type Result struct {
 	Status string
@@ -22,6 +26,12 @@ type Result struct {
 type Description struct {
 	Name        string
 	Description string
+	// Tags are labels used to route notifications, see WithMetadata
+	Tags map[string]string
+	// Severity is the impact of a failure, like critical or warning, see WithMetadata
+	Severity string
+	// Owner is who is responsible for the healthcheck, see WithMetadata
+	Owner Owner
 }
 
 // Healthcheck defines a healthcheck that can be executed
@@ -30,6 +40,22 @@ type Healthcheck interface {
 	Execute() Result
 }
 
+// ContextHealthcheck defines a healthcheck that can be executed with a context.
+// The context carries the logger of the run, see logging.FromContext. It is not cancelled with the run,
+// so a check that has started is allowed to finish, and checks should bound their own duration, e.g. with a timeout.
+type ContextHealthcheck interface {
+	Healthcheck
+	ExecuteContext(ctx context.Context) Result
+}
+
+// Execute runs the healthcheck with the context if it supports one
+func Execute(ctx context.Context, hc Healthcheck) Result {
+	if c, ok := hc.(ContextHealthcheck); ok {
+		return c.ExecuteContext(ctx)
+	}
+	return hc.Execute()
+}
+
 // Fail creates a failed healthcheck result
 func Fail(reason string) Result {
 	return FailWithIO(reason, nil, nil)
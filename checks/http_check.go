@@ -1,6 +1,7 @@
 package checks
 
 import (
+	"context"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
@@ -60,13 +61,18 @@ type HTTPResponseTimeExpectation struct {
 
 // Execute runs the healthcheck
 func (c HTTPGetHealthcheck) Execute() Result {
+	return c.ExecuteContext(context.Background())
+}
+
+// ExecuteContext runs the healthcheck with the context
+func (c HTTPGetHealthcheck) ExecuteContext(ctx context.Context) Result {
 	input := struct {
 		URL string `json:"url"`
 	}{
 		http.CleanURL(c.URL),
 	}
 
-	client := http.NewClient(c.URL)
+	client := http.NewClient(c.URL).WithContext(ctx)
 
 	start := time.Now()
 	resp, err := client.Get("")
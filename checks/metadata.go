@@ -0,0 +1,70 @@
+package checks
+
+import (
+	"context"
+)
+
+// Metadata defines information about a healthcheck that is not needed to execute it, like its tags, severity and owner
+type Metadata struct {
+	Tags     map[string]string
+	Severity string
+	Owner    Owner
+}
+
+// Owner defines who is responsible for a healthcheck and how to act when it fails
+type Owner struct {
+	Team       string `json:"team,omitempty"`
+	RunbookURL string `json:"runbookURL,omitempty"`
+	Contact    string `json:"contact,omitempty"`
+}
+
+// IsZero returns whether no owner is set
+func (o Owner) IsZero() bool {
+	return o == Owner{}
+}
+
+// metadataHealthcheck adds metadata to the description of a healthcheck
+type metadataHealthcheck struct {
+	Healthcheck
+	metadata Metadata
+}
+
+// WithMetadata returns the healthcheck with the metadata added to its description
+func WithMetadata(hc Healthcheck, m Metadata) Healthcheck {
+	return metadataHealthcheck{Healthcheck: hc, metadata: m}
+}
+
+// Describe returns the description of the healthcheck including the metadata
+func (h metadataHealthcheck) Describe() Description {
+	d := h.Healthcheck.Describe()
+	if len(h.metadata.Tags) > 0 {
+		tags := make(map[string]string, len(d.Tags)+len(h.metadata.Tags))
+		for k, v := range d.Tags {
+			tags[k] = v
+		}
+		for k, v := range h.metadata.Tags {
+			tags[k] = v
+		}
+		d.Tags = tags
+	}
+	if h.metadata.Severity != "" {
+		d.Severity = h.metadata.Severity
+	}
+	if !h.metadata.Owner.IsZero() {
+		d.Owner = h.metadata.Owner
+	}
+	return d
+}
+
+// ExecuteContext executes the healthcheck with the context if it supports one
+func (h metadataHealthcheck) ExecuteContext(ctx context.Context) Result {
+	return Execute(ctx, h.Healthcheck)
+}
+
+// Unwrap returns the healthcheck without the metadata added by WithMetadata
+func Unwrap(hc Healthcheck) Healthcheck {
+	if m, ok := hc.(metadataHealthcheck); ok {
+		return m.Healthcheck
+	}
+	return hc
+}
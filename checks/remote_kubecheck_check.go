@@ -0,0 +1,119 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	nethttp "net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/StenaIT/kubecheck/http"
+)
+
+// DefaultRemoteTimeout is the default deadline for all requests of a remote kubecheck healthcheck
+const DefaultRemoteTimeout = 10 * time.Second
+
+// RemoteKubecheckHealthcheck defines a healthcheck that imports the results of another kubecheck instance
+type RemoteKubecheckHealthcheck struct {
+	Name        string
+	Description string
+	URL         string
+	Checks      []string
+	// Timeout is the deadline for all requests to the remote instance, including one per name in Checks. Defaults to DefaultRemoteTimeout.
+	Timeout time.Duration
+}
+
+// RemoteCheckResult defines a healthcheck result reported by a remote kubecheck instance
+type RemoteCheckResult struct {
+	Description string      `json:"description"`
+	Status      string      `json:"status"`
+	Reason      string      `json:"reason,omitempty"`
+	Input       interface{} `json:"input,omitempty"`
+	Output      interface{} `json:"output,omitempty"`
+}
+
+// Execute runs the healthcheck
+func (c RemoteKubecheckHealthcheck) Execute() Result {
+	return c.ExecuteContext(context.Background())
+}
+
+// ExecuteContext runs the healthcheck with the context
+func (c RemoteKubecheckHealthcheck) ExecuteContext(ctx context.Context) Result {
+	input := struct {
+		URL    string   `json:"url"`
+		Checks []string `json:"checks,omitempty"`
+	}{
+		http.CleanURL(c.URL),
+		c.Checks,
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultRemoteTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := http.NewClient(strings.TrimSuffix(c.URL, "/")).WithContext(ctx)
+	client.Client.Timeout = timeout
+
+	paths := []string{"/checks/"}
+	if len(c.Checks) > 0 {
+		paths = make([]string, 0)
+		for _, name := range c.Checks {
+			paths = append(paths, "/checks/"+url.PathEscape(name))
+		}
+	}
+
+	output := make(map[string]RemoteCheckResult)
+	for _, path := range paths {
+		results, err := fetchRemoteResults(client, path)
+		if err != nil {
+			return FailWithIO(err.Error(), input, output)
+		}
+		for name, result := range results {
+			output[name] = result
+		}
+	}
+
+	failed := make([]string, 0)
+	for name, result := range output {
+		if result.Status == Failed {
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return FailWithIO(fmt.Sprintf("remote healthchecks failed: %s", strings.Join(failed, ", ")), input, output)
+	}
+
+	return PassWithIO(input, output)
+}
+
+// Describe returns the description of the healthcheck
+func (c RemoteKubecheckHealthcheck) Describe() Description {
+	return Description{
+		Name:        c.Name,
+		Description: c.Description,
+	}
+}
+
+func fetchRemoteResults(client *http.Client, path string) (map[string]RemoteCheckResult, error) {
+	resp, err := client.Get(path)
+	if err != nil {
+		return nil, fmt.Errorf("unreachable: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Failed checks are reported with a configurable status code, so any response with results is accepted
+	results := make(map[string]RemoteCheckResult)
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("invalid response from %s (%d %s): %v", path, resp.StatusCode, nethttp.StatusText(resp.StatusCode), err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,337 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+// CheckSpec defines the fields shared by all declarative healthchecks
+type CheckSpec struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Tags, Severity and Owner are added to the description of the healthcheck, see checks.WithMetadata
+	Tags     map[string]string `json:"tags"`
+	Severity string            `json:"severity"`
+	Owner    checks.Owner      `json:"owner"`
+	raw      json.RawMessage
+}
+
+// Decode decodes the full healthcheck definition into v, rejecting unknown fields.
+// v should embed CheckSpec to accept the shared fields.
+func (s CheckSpec) Decode(v interface{}) error {
+	return decodeStrict(s.raw, v)
+}
+
+// CheckBuilder creates a healthcheck from its declarative definition
+type CheckBuilder func(spec CheckSpec) (checks.Healthcheck, error)
+
+var checkTypes = struct {
+	sync.RWMutex
+	builders map[string]CheckBuilder
+}{
+	builders: make(map[string]CheckBuilder),
+}
+
+// RegisterCheckType makes a healthcheck type available to declarative configuration
+func RegisterCheckType(name string, builder CheckBuilder) {
+	checkTypes.Lock()
+	defer checkTypes.Unlock()
+	checkTypes.builders[name] = builder
+}
+
+func init() {
+	RegisterCheckType("random-fail", buildRandomFailHealthcheck)
+	RegisterCheckType("http-get", buildHTTPGetHealthcheck)
+	RegisterCheckType("dns-lookup", buildDNSLookupHealthcheck)
+	RegisterCheckType("kubernetes-node", buildKubernetesNodeHealthcheck)
+	RegisterCheckType("kubernetes-pod", buildKubernetesPodHealthcheck)
+	RegisterCheckType("kubernetes-pod-anti-affinity", buildKubernetesPodAntiAffinityHealthcheck)
+	RegisterCheckType("kubernetes-traefik", buildKubernetesTraefikHealthcheck)
+	RegisterCheckType("remote-kubecheck", buildRemoteKubecheckHealthcheck)
+}
+
+func buildHealthcheck(raw json.RawMessage) (checks.Healthcheck, error) {
+	spec := CheckSpec{}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, err
+	}
+	spec.raw = raw
+
+	if spec.Name == "" {
+		return nil, fmt.Errorf("missing name")
+	}
+
+	checkTypes.RLock()
+	builder, ok := checkTypes.builders[spec.Type]
+	checkTypes.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown type \"%s\"", spec.Name, spec.Type)
+	}
+
+	hc, err := builder(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", spec.Name, err)
+	}
+
+	if len(spec.Tags) > 0 || spec.Severity != "" || !spec.Owner.IsZero() {
+		hc = checks.WithMetadata(hc, checks.Metadata{Tags: spec.Tags, Severity: spec.Severity, Owner: spec.Owner})
+	}
+
+	return hc, nil
+}
+
+func buildRandomFailHealthcheck(spec CheckSpec) (checks.Healthcheck, error) {
+	s := struct {
+		CheckSpec
+		FailRate int `json:"failRate"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return checks.RandomFailHealthcheck{
+		Name:        s.Name,
+		Description: s.Description,
+		FailRate:    s.FailRate,
+	}, nil
+}
+
+func buildHTTPGetHealthcheck(spec CheckSpec) (checks.Healthcheck, error) {
+	s := struct {
+		CheckSpec
+		URL    string `json:"url"`
+		Expect struct {
+			StatusCode          int               `json:"statusCode"`
+			StatusCodeMin       int               `json:"statusCodeMin"`
+			StatusCodeMax       int               `json:"statusCodeMax"`
+			BodyEquals          string            `json:"bodyEquals"`
+			BodyContains        string            `json:"bodyContains"`
+			Headers             map[string]string `json:"headers"`
+			ValidCertificate    bool              `json:"validCertificate"`
+			CertificateDaysLeft int               `json:"certificateDaysLeft"`
+			ResponseTime        Duration          `json:"responseTime"`
+		} `json:"expect"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	if s.URL == "" {
+		return nil, fmt.Errorf("missing url")
+	}
+
+	e := s.Expect
+	expectations := make([]checks.HTTPResponseExpectation, 0)
+
+	if e.StatusCode != 0 {
+		expectations = append(expectations, checks.ExpectStatusCode(e.StatusCode))
+	} else if e.StatusCodeMin != 0 || e.StatusCodeMax != 0 {
+		expectations = append(expectations, checks.ExpectStatusCodeRange(e.StatusCodeMin, e.StatusCodeMax))
+	}
+	if e.BodyEquals != "" {
+		expectations = append(expectations, checks.ExpectBodyEquals(e.BodyEquals))
+	}
+	if e.BodyContains != "" {
+		expectations = append(expectations, checks.ExpectBodyContains(e.BodyContains))
+	}
+	for header, value := range e.Headers {
+		expectations = append(expectations, checks.ExpectHeader(header, value))
+	}
+	if e.ValidCertificate || e.CertificateDaysLeft > 0 {
+		expectations = append(expectations, checks.ExpectValidCertificate(e.CertificateDaysLeft))
+	}
+	if e.ResponseTime > 0 {
+		expectations = append(expectations, checks.HTTPResponseTimeExpectation{Expected: time.Duration(e.ResponseTime)})
+	}
+
+	return checks.HTTPGetHealthcheck{
+		Name:        s.Name,
+		Description: s.Description,
+		URL:         s.URL,
+	}.WithExpectations(expectations...), nil
+}
+
+func buildDNSLookupHealthcheck(spec CheckSpec) (checks.Healthcheck, error) {
+	s := struct {
+		CheckSpec
+		Host   string `json:"host"`
+		Expect struct {
+			Addrs []string `json:"addrs"`
+		} `json:"expect"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	if s.Host == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+
+	hc := checks.DNSLookupHealthcheck{
+		Name:        s.Name,
+		Description: s.Description,
+		Host:        s.Host,
+	}
+
+	if len(s.Expect.Addrs) > 0 {
+		hc = hc.WithExpectations(checks.ExpectAddrs(s.Expect.Addrs...))
+	}
+
+	return hc, nil
+}
+
+func buildKubernetesNodeHealthcheck(spec CheckSpec) (checks.Healthcheck, error) {
+	s := struct {
+		CheckSpec
+		Expect struct {
+			NodeCount             int      `json:"nodeCount"`
+			NodeCountMin          int      `json:"nodeCountMin"`
+			NodeCountMax          int      `json:"nodeCountMax"`
+			NodeStatusOK          bool     `json:"nodeStatusOK"`
+			NodeStatusGracePeriod Duration `json:"nodeStatusGracePeriod"`
+		} `json:"expect"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	e := s.Expect
+	expectations := make([]checks.KubernetesNodeExpectation, 0)
+
+	switch {
+	case e.NodeCount != 0:
+		expectations = append(expectations, checks.ExpectNodeCount(e.NodeCount))
+	case e.NodeCountMin != 0 && e.NodeCountMax != 0:
+		expectations = append(expectations, checks.ExpectNodeCountRange(e.NodeCountMin, e.NodeCountMax))
+	case e.NodeCountMin != 0:
+		expectations = append(expectations, checks.ExpectNodeCountMin(e.NodeCountMin))
+	case e.NodeCountMax != 0:
+		expectations = append(expectations, checks.ExpectNodeCountMax(e.NodeCountMax))
+	}
+	if e.NodeStatusOK || e.NodeStatusGracePeriod > 0 {
+		expectations = append(expectations, checks.ExpectNodeStatusOK(time.Duration(e.NodeStatusGracePeriod)))
+	}
+
+	return checks.KubernetesNodeHealthcheck{
+		Name:        s.Name,
+		Description: s.Description,
+	}.WithExpectations(expectations...), nil
+}
+
+func buildKubernetesPodHealthcheck(spec CheckSpec) (checks.Healthcheck, error) {
+	s := struct {
+		CheckSpec
+		Namespace   string   `json:"namespace"`
+		GracePeriod Duration `json:"gracePeriod"`
+		ExcludePods []string `json:"excludePods"`
+		Expect      struct {
+			PodStatusOK          bool   `json:"podStatusOK"`
+			MaxContainerRestarts *int32 `json:"maxContainerRestarts"`
+		} `json:"expect"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	hc := checks.NewKubernetesPodHealthcheck(s.Name, checks.KubernetesPodConfig{
+		Namespace:          s.Namespace,
+		CreatedGracePeriod: time.Duration(s.GracePeriod),
+		ExcludePods:        s.ExcludePods,
+	})
+	if s.Description != "" {
+		hc.Description = s.Description
+	}
+
+	if s.Expect.PodStatusOK {
+		hc = hc.WithExpectations(checks.ExpectPodStatusOK())
+	}
+	if s.Expect.MaxContainerRestarts != nil {
+		hc = hc.WithExpectations(checks.ExpectPodMaxContainerRestarts(*s.Expect.MaxContainerRestarts))
+	}
+
+	return hc, nil
+}
+
+func buildKubernetesPodAntiAffinityHealthcheck(spec CheckSpec) (checks.Healthcheck, error) {
+	s := struct {
+		CheckSpec
+		ExcludeNamespaces  []string `json:"excludeNamespaces"`
+		ExcludeDeployments []string `json:"excludeDeployments"`
+		Expect             struct {
+			NodeSpread int `json:"nodeSpread"`
+		} `json:"expect"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	hc := checks.KubernetesPodAntiAffinityHealthcheck{
+		Name:               s.Name,
+		Description:        s.Description,
+		ExcludeNamespaces:  s.ExcludeNamespaces,
+		ExcludeDeployments: s.ExcludeDeployments,
+	}
+
+	if s.Expect.NodeSpread > 0 {
+		hc = hc.WithExpectations(checks.ExpectNodeSpread(s.Expect.NodeSpread))
+	}
+
+	return hc, nil
+}
+
+func buildKubernetesTraefikHealthcheck(spec CheckSpec) (checks.Healthcheck, error) {
+	s := struct {
+		CheckSpec
+		Namespace       string   `json:"namespace"`
+		DaemonSetName   string   `json:"daemonsetName"`
+		ServiceName     string   `json:"serviceName"`
+		ServicePortName string   `json:"servicePort"`
+		GracePeriod     Duration `json:"gracePeriod"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	hc := checks.NewKubernetesTraefikHealthcheck(s.Name, checks.KubernetesTraefikConfig{
+		Namespace:       s.Namespace,
+		DaemonSetName:   s.DaemonSetName,
+		ServiceName:     s.ServiceName,
+		ServicePortName: s.ServicePortName,
+		GracePeriod:     time.Duration(s.GracePeriod),
+	})
+	if s.Description != "" {
+		hc.Description = s.Description
+	}
+
+	return hc, nil
+}
+
+func buildRemoteKubecheckHealthcheck(spec CheckSpec) (checks.Healthcheck, error) {
+	s := struct {
+		CheckSpec
+		URL     string   `json:"url"`
+		Checks  []string `json:"checks"`
+		Timeout Duration `json:"timeout"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	if s.URL == "" {
+		return nil, fmt.Errorf("missing url")
+	}
+
+	return checks.RemoteKubecheckHealthcheck{
+		Name:        s.Name,
+		Description: s.Description,
+		URL:         s.URL,
+		Checks:      s.Checks,
+		Timeout:     time.Duration(s.Timeout),
+	}, nil
+}
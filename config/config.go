@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/StenaIT/kubecheck/checks"
 	"github.com/StenaIT/kubecheck/hook"
 
@@ -13,22 +15,195 @@ const OnHealthcheckStartedEvent hook.Event = "OnHealthcheckStarted"
 // OnHealthcheckCompletedEvent represents the hook event OnHealthcheckCompleted
 const OnHealthcheckCompletedEvent hook.Event = "OnHealthcheckCompleted"
 
+// OnCheckFailedEvent represents the hook event OnCheckFailed, triggered when a check starts failing
+const OnCheckFailedEvent hook.Event = "OnCheckFailed"
+
+// OnCheckRecoveredEvent represents the hook event OnCheckRecovered, triggered when a failing check passes again
+const OnCheckRecoveredEvent hook.Event = "OnCheckRecovered"
+
+// OnCheckStillFailingEvent represents the hook event OnCheckStillFailing, triggered at the reminder interval while a check keeps failing
+const OnCheckStillFailingEvent hook.Event = "OnCheckStillFailing"
+
+// OnRunFailedEvent represents the hook event OnRunFailed, triggered when a run has completed with failed checks
+const OnRunFailedEvent hook.Event = "OnRunFailed"
+
+// OnChecksChangedEvent represents the hook event OnChecksChanged, triggered with the per-check events batched within the group wait
+const OnChecksChangedEvent hook.Event = "OnChecksChanged"
+
+// Events are the known hook events
+var Events = []hook.Event{
+	OnHealthcheckStartedEvent,
+	OnHealthcheckCompletedEvent,
+	OnCheckFailedEvent,
+	OnCheckRecoveredEvent,
+	OnCheckStillFailingEvent,
+	OnRunFailedEvent,
+	OnChecksChangedEvent,
+}
+
 // Kubecheck defines the context for Kubecheck
 type Kubecheck struct {
 	Config       *KubecheckConfig
 	Healthchecks []checks.Healthcheck
+	Groups       []Group
 	Router       *mux.Router
 }
 
+// Group defines a named subset of the healthchecks exposed at /groups/<name>/
+type Group struct {
+	Name        string
+	Description string
+	Checks      []string
+	Webhooks    []hook.Webhook
+	Notifiers   []hook.Notifier
+	API         APIConfig
+}
+
 // KubecheckConfig defines the configuration for Kubecheck
 type KubecheckConfig struct {
-	Debug    bool
-	LogLevel string
-	Webhooks []hook.Webhook
-	API      APIConfig
+	Debug         bool
+	LogLevel      string
+	Logging       LoggingConfig
+	Webhooks      []hook.Webhook
+	Notifiers     []hook.Notifier
+	Routing       hook.Routing
+	Notifications NotificationsConfig
+	Delivery      hook.DeliveryConfig
+	API           APIConfig
+	Server        ServerConfig
+	Admin         AdminConfig
+	Federation    FederationConfig
+	History       HistoryConfig
+	StatusPage    StatusPageConfig
+	Redaction     RedactionConfig
+}
+
+// LoggingConfig defines how log entries are written, see logging.Configure
+type LoggingConfig struct {
+	// Format is text, json or logfmt. Defaults to text.
+	Format string
+	// Levels overrides LogLevel for subsystems by their service name, e.g. "HTTP-Client", "Hooks" or "Checks"
+	Levels map[string]string
+}
+
+// NotificationsConfig defines the configuration for per-check hook events
+type NotificationsConfig struct {
+	// ReminderInterval is how often OnCheckStillFailing is triggered while a check keeps failing. Defaults to one hour.
+	// It is also the repeat interval of OnChecksChanged, whose groups batch these reminders again.
+	ReminderInterval time.Duration
+	// GroupWait is how long per-check events are batched into a single OnChecksChanged event. Defaults to 30 seconds.
+	GroupWait time.Duration
 }
 
 // APIConfig defines the configuration for the API
 type APIConfig struct {
 	ForceOKStatusCode bool
+	// FailedStatusCode is returned when a check fails. Defaults to 424 Failed Dependency.
+	FailedStatusCode int
+	// BadgeMaxAge is the maximum age of a cached result used for badges. Defaults to one minute.
+	BadgeMaxAge time.Duration
+}
+
+// ForGroup returns the configuration used when running the healthchecks of a group
+func (c *KubecheckConfig) ForGroup(g Group) *KubecheckConfig {
+	gc := *c
+	gc.Webhooks = g.Webhooks
+	gc.Notifiers = g.Notifiers
+	gc.API = g.API
+	return &gc
+}
+
+// Subscribers returns the webhooks and notifiers notified about hook events, narrowed to the checks routed to them
+func (c *KubecheckConfig) Subscribers() []hook.Notifier {
+	return c.Routing.Apply(append(hook.Notifiers(c.Webhooks), c.Notifiers...))
+}
+
+// HealthchecksOf returns the healthchecks of a group
+func (k *Kubecheck) HealthchecksOf(g Group) []checks.Healthcheck {
+	hcks := make([]checks.Healthcheck, 0)
+	for _, name := range g.Checks {
+		for _, hc := range k.Healthchecks {
+			if hc.Describe().Name == name {
+				hcks = append(hcks, hc)
+			}
+		}
+	}
+	return hcks
+}
+
+// AllChecksBadge is the name of the badge of all checks, served at /badges/all.svg, so no healthcheck may use it
+const AllChecksBadge = "all"
+
+// WriteTimeout is the time the HTTP server has to write a response
+const WriteTimeout = 30 * time.Second
+
+// ServerConfig defines the configuration for the HTTP server
+type ServerConfig struct {
+	// Address to listen on. Defaults to ":8113".
+	Address string
+	// ShutdownDelay is the time to keep serving after /readyz starts failing, allowing load balancers to react.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is the deadline for draining requests and healthcheck runs. Defaults to 30 seconds.
+	ShutdownTimeout time.Duration
+}
+
+// AdminConfig defines the configuration for the admin API
+type AdminConfig struct {
+	// Token is the bearer token required by the admin API. The admin API is disabled if empty.
+	Token string
+}
+
+// RedactionConfig defines secrets that are masked in healthcheck results before they are logged, returned or stored.
+// The values are added to the defaults of the redact package.
+type RedactionConfig struct {
+	// Fields are field and header names whose values are masked, e.g. "X-Vault-Token"
+	Fields []string
+	// QueryParams are query-string keys whose values are masked in URLs
+	QueryParams []string
+	// Patterns are regular expressions whose matches are masked in strings
+	Patterns []string
+}
+
+// FederationConfig defines the remote kubecheck instances aggregated at /federation/
+type FederationConfig struct {
+	Peers []Peer
+}
+
+// Peer defines a remote kubecheck instance
+type Peer struct {
+	Cluster string
+	URL     string
+	// Checks limits the imported results to the named checks. All checks are imported if empty.
+	Checks []string
+	// Timeout is the deadline for all requests to the peer. Defaults to 10 seconds and must be below WriteTimeout,
+	// so an unreachable peer is reported as a failed cluster instead of failing the whole response.
+	Timeout time.Duration
+}
+
+// HistoryConfig defines the configuration for the result history
+type HistoryConfig struct {
+	// Path of the on-disk database. Results are only kept in memory if empty.
+	Path string
+	// Retention is the maximum age of stored results. Defaults to 30 days.
+	Retention time.Duration
+	// SLO is the default availability objective in percent used for reports. Defaults to 99.9.
+	SLO float64
+}
+
+// StatusPageConfig defines the configuration for the public status page
+type StatusPageConfig struct {
+	Enabled bool
+	Title   string
+	// Address serves the status page on its own listener, e.g. ":8114". It is served under /status/ if empty.
+	Address string
+	// Window is how far back incidents are shown. Defaults to 7 days.
+	Window     time.Duration
+	Components []Component
+}
+
+// Component defines a customer facing component and the healthchecks it depends on
+type Component struct {
+	Name        string
+	Description string
+	Checks      []string
 }
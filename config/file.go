@@ -0,0 +1,466 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+	"github.com/StenaIT/kubecheck/logging"
+	"github.com/StenaIT/kubecheck/redact"
+
+	"sigs.k8s.io/yaml"
+)
+
+// File defines the declarative configuration of kubecheck, usually read from YAML or JSON
+type File struct {
+	Debug         bool                    `json:"debug"`
+	LogLevel      string                  `json:"logLevel"`
+	Logging       LoggingConfig           `json:"logging"`
+	API           APIFileConfig           `json:"api"`
+	Server        ServerFileConfig        `json:"server"`
+	Admin         AdminConfig             `json:"admin"`
+	Federation    FederationFileConfig    `json:"federation"`
+	History       HistoryFileConfig       `json:"history"`
+	StatusPage    StatusPageFileConfig    `json:"statusPage"`
+	Redaction     RedactionConfig         `json:"redaction"`
+	Webhooks      []hook.Webhook          `json:"webhooks"`
+	Notifiers     []json.RawMessage       `json:"notifiers"`
+	Routing       hook.Routing            `json:"routing"`
+	Notifications NotificationsFileConfig `json:"notifications"`
+	Delivery      DeliveryFileConfig      `json:"delivery"`
+	Checks        []json.RawMessage       `json:"checks"`
+	Groups        []GroupFileConfig       `json:"groups"`
+}
+
+// GroupFileConfig defines the declarative configuration for a group
+type GroupFileConfig struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Checks      []string          `json:"checks"`
+	Webhooks    []hook.Webhook    `json:"webhooks"`
+	Notifiers   []json.RawMessage `json:"notifiers"`
+	API         APIFileConfig     `json:"api"`
+}
+
+// APIFileConfig defines the declarative configuration for the API
+type APIFileConfig struct {
+	ForceOKStatusCode bool     `json:"forceOKStatusCode"`
+	FailedStatusCode  int      `json:"failedStatusCode"`
+	BadgeMaxAge       Duration `json:"badgeMaxAge"`
+}
+
+func (c APIFileConfig) apiConfig() APIConfig {
+	return APIConfig{
+		ForceOKStatusCode: c.ForceOKStatusCode,
+		FailedStatusCode:  c.FailedStatusCode,
+		BadgeMaxAge:       time.Duration(c.BadgeMaxAge),
+	}
+}
+
+// FederationFileConfig defines the declarative configuration for federation
+type FederationFileConfig struct {
+	Peers []struct {
+		Cluster string   `json:"cluster"`
+		URL     string   `json:"url"`
+		Checks  []string `json:"checks"`
+		Timeout Duration `json:"timeout"`
+	} `json:"peers"`
+}
+
+// NotificationsFileConfig defines the declarative configuration for per-check hook events
+type NotificationsFileConfig struct {
+	ReminderInterval Duration `json:"reminderInterval"`
+	GroupWait        Duration `json:"groupWait"`
+}
+
+// DeliveryFileConfig defines the declarative configuration for webhook delivery
+type DeliveryFileConfig struct {
+	Timeout    Duration `json:"timeout"`
+	Retries    int      `json:"retries"`
+	Backoff    Duration `json:"backoff"`
+	MaxBackoff Duration `json:"maxBackoff"`
+	QueueSize  int      `json:"queueSize"`
+}
+
+// HistoryFileConfig defines the declarative configuration for the result history
+type HistoryFileConfig struct {
+	Path      string   `json:"path"`
+	Retention Duration `json:"retention"`
+	SLO       float64  `json:"slo"`
+}
+
+// StatusPageFileConfig defines the declarative configuration for the status page
+type StatusPageFileConfig struct {
+	Enabled    bool     `json:"enabled"`
+	Title      string   `json:"title"`
+	Address    string   `json:"address"`
+	Window     Duration `json:"window"`
+	Components []struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Checks      []string `json:"checks"`
+	} `json:"components"`
+}
+
+// ServerFileConfig defines the declarative configuration for the HTTP server
+type ServerFileConfig struct {
+	Address         string   `json:"address"`
+	ShutdownDelay   Duration `json:"shutdownDelay"`
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+}
+
+// Duration is a time.Duration that is written as a string like "30s" in config files
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid duration %s, expected a string like \"30s\"", string(b))
+	}
+
+	pd, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(pd)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadFile reads a declarative configuration file and creates the kubecheck context
+func LoadFile(path string) (*Kubecheck, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	kubecheck, err := Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return kubecheck, nil
+}
+
+// Load parses a declarative configuration in YAML or JSON and creates the kubecheck context
+func Load(data []byte) (*Kubecheck, error) {
+	f := File{}
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, err
+	}
+
+	kubecheck := &Kubecheck{
+		Config: &KubecheckConfig{
+			Debug:    f.Debug,
+			LogLevel: f.LogLevel,
+			Logging:  f.Logging,
+			Webhooks: f.Webhooks,
+			Routing:  f.Routing,
+			Notifications: NotificationsConfig{
+				ReminderInterval: time.Duration(f.Notifications.ReminderInterval),
+				GroupWait:        time.Duration(f.Notifications.GroupWait),
+			},
+			Delivery: hook.DeliveryConfig{
+				Timeout:    time.Duration(f.Delivery.Timeout),
+				Retries:    f.Delivery.Retries,
+				Backoff:    time.Duration(f.Delivery.Backoff),
+				MaxBackoff: time.Duration(f.Delivery.MaxBackoff),
+				QueueSize:  f.Delivery.QueueSize,
+			},
+			API: f.API.apiConfig(),
+			Server: ServerConfig{
+				Address:         f.Server.Address,
+				ShutdownDelay:   time.Duration(f.Server.ShutdownDelay),
+				ShutdownTimeout: time.Duration(f.Server.ShutdownTimeout),
+			},
+			Admin:     f.Admin,
+			Redaction: f.Redaction,
+			History: HistoryConfig{
+				Path:      f.History.Path,
+				Retention: time.Duration(f.History.Retention),
+				SLO:       f.History.SLO,
+			},
+		},
+		Healthchecks: make([]checks.Healthcheck, 0),
+		Groups:       make([]Group, 0),
+	}
+
+	kubecheck.Config.StatusPage = StatusPageConfig{
+		Enabled: f.StatusPage.Enabled,
+		Title:   f.StatusPage.Title,
+		Address: f.StatusPage.Address,
+		Window:  time.Duration(f.StatusPage.Window),
+	}
+	for _, c := range f.StatusPage.Components {
+		kubecheck.Config.StatusPage.Components = append(kubecheck.Config.StatusPage.Components, Component{
+			Name:        c.Name,
+			Description: c.Description,
+			Checks:      c.Checks,
+		})
+	}
+
+	for _, p := range f.Federation.Peers {
+		kubecheck.Config.Federation.Peers = append(kubecheck.Config.Federation.Peers, Peer{
+			Cluster: p.Cluster,
+			URL:     p.URL,
+			Checks:  p.Checks,
+			Timeout: time.Duration(p.Timeout),
+		})
+	}
+
+	for i, raw := range f.Checks {
+		hc, err := buildHealthcheck(raw)
+		if err != nil {
+			return nil, fmt.Errorf("checks[%d]: %v", i, err)
+		}
+		kubecheck.Healthchecks = append(kubecheck.Healthchecks, hc)
+	}
+
+	notifiers, err := buildNotifiers(f.Notifiers)
+	if err != nil {
+		return nil, err
+	}
+	kubecheck.Config.Notifiers = notifiers
+
+	for _, g := range f.Groups {
+		notifiers, err := buildNotifiers(g.Notifiers)
+		if err != nil {
+			return nil, fmt.Errorf("group \"%s\": %v", g.Name, err)
+		}
+
+		kubecheck.Groups = append(kubecheck.Groups, Group{
+			Name:        g.Name,
+			Description: g.Description,
+			Checks:      g.Checks,
+			Webhooks:    g.Webhooks,
+			Notifiers:   notifiers,
+			API:         g.API.apiConfig(),
+		})
+	}
+
+	if err := Validate(kubecheck); err != nil {
+		return nil, err
+	}
+
+	return kubecheck, nil
+}
+
+// Validate verifies that the kubecheck context can be served
+func Validate(kubecheck *Kubecheck) error {
+	if kubecheck.Config == nil {
+		return fmt.Errorf("missing config")
+	}
+
+	names := make(map[string]bool)
+	for _, hc := range kubecheck.Healthchecks {
+		name := hc.Describe().Name
+		if name == "" {
+			return fmt.Errorf("healthcheck of type %T has no name", hc)
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate healthcheck name \"%s\"", name)
+		}
+		if name == AllChecksBadge {
+			return fmt.Errorf("healthcheck name \"%s\" is reserved for the badge of all checks", name)
+		}
+		names[name] = true
+	}
+
+	if err := validateWebhooks(kubecheck.Config.Webhooks); err != nil {
+		return err
+	}
+	if err := validateNotifiers(kubecheck.Config.Notifiers); err != nil {
+		return err
+	}
+	if err := validateSubscribers(kubecheck.Config.Webhooks, kubecheck.Config.Notifiers); err != nil {
+		return err
+	}
+
+	if _, err := logging.NewHandler(ioutil.Discard, kubecheck.Config.Logging.Format); err != nil {
+		return err
+	}
+	if _, _, err := logging.ParseLevels(kubecheck.Config.LogLevel, kubecheck.Config.Logging.Levels); err != nil {
+		return err
+	}
+
+	r := kubecheck.Config.Redaction
+	if _, err := redact.New(r.Fields, r.QueryParams, r.Patterns); err != nil {
+		return err
+	}
+
+	if slo := kubecheck.Config.History.SLO; slo < 0 || slo >= 100 {
+		return fmt.Errorf("history slo must be between 0 and 100, got %v", slo)
+	}
+
+	clusters := make(map[string]bool)
+	for _, p := range kubecheck.Config.Federation.Peers {
+		if p.Cluster == "" || p.URL == "" {
+			return fmt.Errorf("federation peers require a cluster and url")
+		}
+		if clusters[p.Cluster] {
+			return fmt.Errorf("duplicate federation cluster \"%s\"", p.Cluster)
+		}
+		if p.Timeout < 0 || p.Timeout >= WriteTimeout {
+			return fmt.Errorf("federation cluster \"%s\" has a timeout of %s, which must be below %s", p.Cluster, p.Timeout, WriteTimeout)
+		}
+		clusters[p.Cluster] = true
+	}
+
+	components := make(map[string]bool)
+	for _, c := range kubecheck.Config.StatusPage.Components {
+		if c.Name == "" {
+			return fmt.Errorf("status page component has no name")
+		}
+		if components[c.Name] {
+			return fmt.Errorf("duplicate status page component \"%s\"", c.Name)
+		}
+		components[c.Name] = true
+
+		for _, name := range c.Checks {
+			if !names[name] {
+				return fmt.Errorf("status page component \"%s\": unknown healthcheck \"%s\"", c.Name, name)
+			}
+		}
+	}
+
+	groups := make(map[string]bool)
+	for _, g := range kubecheck.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("group has no name")
+		}
+		if groups[g.Name] {
+			return fmt.Errorf("duplicate group name \"%s\"", g.Name)
+		}
+		groups[g.Name] = true
+
+		for _, name := range g.Checks {
+			if !names[name] {
+				return fmt.Errorf("group \"%s\": unknown healthcheck \"%s\"", g.Name, name)
+			}
+		}
+
+		if err := validateWebhooks(g.Webhooks); err != nil {
+			return fmt.Errorf("group \"%s\": %v", g.Name, err)
+		}
+		if err := validateNotifiers(g.Notifiers); err != nil {
+			return fmt.Errorf("group \"%s\": %v", g.Name, err)
+		}
+		if err := validateSubscribers(g.Webhooks, g.Notifiers); err != nil {
+			return fmt.Errorf("group \"%s\": %v", g.Name, err)
+		}
+	}
+
+	notifiers := subscriberNames(kubecheck.Config.Webhooks, kubecheck.Config.Notifiers)
+	for _, g := range kubecheck.Groups {
+		notifiers = append(notifiers, subscriberNames(g.Webhooks, g.Notifiers)...)
+	}
+	if err := kubecheck.Config.Routing.Validate(notifiers); err != nil {
+		return fmt.Errorf("routing: %v", err)
+	}
+
+	return nil
+}
+
+func validateWebhooks(hooks []hook.Webhook) error {
+	names := make(map[string]bool)
+	for _, wh := range hooks {
+		if wh.Name == "" {
+			return fmt.Errorf("webhook has no name")
+		}
+		if names[wh.Name] {
+			return fmt.Errorf("duplicate webhook name \"%s\"", wh.Name)
+		}
+		names[wh.Name] = true
+
+		if wh.URL == "" {
+			return fmt.Errorf("webhook \"%s\" has no url", wh.Name)
+		}
+		if len(wh.Events) == 0 {
+			return fmt.Errorf("webhook \"%s\" has no events", wh.Name)
+		}
+		for _, e := range wh.Events {
+			if !isKnownEvent(e) {
+				return fmt.Errorf("webhook \"%s\" has an unknown event \"%s\"", wh.Name, e)
+			}
+		}
+		if err := wh.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateNotifiers(notifiers []hook.Notifier) error {
+	names := make(map[string]bool)
+	for _, n := range notifiers {
+		if err := n.Validate(); err != nil {
+			return err
+		}
+
+		d := n.Describe()
+		if names[d.Name] {
+			return fmt.Errorf("duplicate notifier name \"%s\"", d.Name)
+		}
+		names[d.Name] = true
+
+		subscribed := false
+		for _, e := range Events {
+			if n.Subscribes(e) {
+				subscribed = true
+			}
+		}
+		if !subscribed {
+			return fmt.Errorf("%s notifier \"%s\" has no known events", d.Type, d.Name)
+		}
+	}
+	return nil
+}
+
+// validateSubscribers checks that webhooks and notifiers do not share a name, since routes and groups refer to them by name
+func validateSubscribers(hooks []hook.Webhook, notifiers []hook.Notifier) error {
+	names := make(map[string]bool)
+	for _, name := range subscriberNames(hooks, notifiers) {
+		if names[name] {
+			return fmt.Errorf("webhook and notifier share the name \"%s\"", name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+func subscriberNames(hooks []hook.Webhook, notifiers []hook.Notifier) []string {
+	names := make([]string, 0, len(hooks)+len(notifiers))
+	for _, wh := range hooks {
+		names = append(names, wh.Name)
+	}
+	for _, n := range notifiers {
+		names = append(names, n.Describe().Name)
+	}
+	return names
+}
+
+func isKnownEvent(e hook.Event) bool {
+	for _, known := range Events {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeStrict decodes JSON into v, rejecting unknown fields
+func decodeStrict(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	return d.Decode(v)
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+	"github.com/StenaIT/kubecheck/notify"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			name: "valid",
+			data: `
+checks:
+  - type: random-fail
+    name: random
+    failRate: 10
+webhooks:
+  - name: chat
+    url: http://chat
+    events: [OnCheckFailed]
+notifiers:
+  - type: slack
+    name: platform-slack
+    url: http://slack
+    events: [OnCheckFailed]
+federation:
+  peers:
+    - cluster: eu
+      url: http://eu
+      timeout: 5s
+groups:
+  - name: core
+    checks: [random]
+    webhooks:
+      - name: chat
+        url: http://core-chat
+        events: [OnCheckRecovered]
+`,
+		},
+		{
+			name: "unknown field",
+			data: "unknown: true",
+			err:  "unknown field",
+		},
+		{
+			name: "unknown check type",
+			data: "checks:\n  - type: ping\n    name: ping",
+			err:  "checks[0]",
+		},
+		{
+			name: "webhook without name",
+			data: "webhooks:\n  - url: http://chat\n    events: [OnCheckFailed]",
+			err:  "webhook has no name",
+		},
+		{
+			name: "duplicate webhook",
+			data: "webhooks:\n  - name: chat\n    url: http://a\n    events: [OnCheckFailed]\n  - name: chat\n    url: http://b\n    events: [OnCheckFailed]",
+			err:  "duplicate webhook name \"chat\"",
+		},
+		{
+			name: "duplicate group webhook",
+			data: "groups:\n  - name: core\n    webhooks:\n      - name: chat\n        url: http://a\n        events: [OnCheckFailed]\n      - name: chat\n        url: http://b\n        events: [OnCheckFailed]",
+			err:  "group \"core\": duplicate webhook name \"chat\"",
+		},
+		{
+			name: "webhook without events",
+			data: "webhooks:\n  - name: chat\n    url: http://chat",
+			err:  "webhook \"chat\" has no events",
+		},
+		{
+			name: "notifier without name",
+			data: "notifiers:\n  - type: slack\n    url: http://slack\n    events: [OnCheckFailed]",
+			err:  "missing name",
+		},
+		{
+			name: "peer timeout",
+			data: "federation:\n  peers:\n    - cluster: eu\n      url: http://eu\n      timeout: 30s",
+			err:  "federation cluster \"eu\" has a timeout of 30s, which must be below 30s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubecheck, err := Load([]byte(tt.data))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("Load() error: %v", err)
+				}
+				if len(kubecheck.Healthchecks) != 1 || len(kubecheck.Groups) != 1 || kubecheck.Config.Federation.Peers[0].Timeout != 5*time.Second {
+					t.Errorf("Load() = %+v", kubecheck)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Load() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	webhook := func(name string) hook.Webhook {
+		return hook.Webhook{Name: name, URL: "http://" + name, Events: []hook.Event{"OnCheckFailed"}}
+	}
+
+	tests := []struct {
+		name      string
+		kubecheck *Kubecheck
+		err       string
+	}{
+		{
+			name:      "valid",
+			kubecheck: &Kubecheck{Config: &KubecheckConfig{Webhooks: []hook.Webhook{webhook("a"), webhook("b")}}},
+		},
+		{
+			name:      "missing config",
+			kubecheck: &Kubecheck{},
+			err:       "missing config",
+		},
+		{
+			name: "duplicate check",
+			kubecheck: &Kubecheck{
+				Config:       &KubecheckConfig{},
+				Healthchecks: []checks.Healthcheck{checks.RandomFailHealthcheck{Name: "a"}, checks.RandomFailHealthcheck{Name: "a"}},
+			},
+			err: "duplicate healthcheck name \"a\"",
+		},
+		{
+			name: "reserved check name",
+			kubecheck: &Kubecheck{
+				Config:       &KubecheckConfig{},
+				Healthchecks: []checks.Healthcheck{checks.RandomFailHealthcheck{Name: "all"}},
+			},
+			err: "healthcheck name \"all\" is reserved",
+		},
+		{
+			name:      "webhook without name",
+			kubecheck: &Kubecheck{Config: &KubecheckConfig{Webhooks: []hook.Webhook{webhook("")}}},
+			err:       "webhook has no name",
+		},
+		{
+			name:      "duplicate webhook",
+			kubecheck: &Kubecheck{Config: &KubecheckConfig{Webhooks: []hook.Webhook{webhook("a"), webhook("a")}}},
+			err:       "duplicate webhook name \"a\"",
+		},
+		{
+			name:      "unknown event",
+			kubecheck: &Kubecheck{Config: &KubecheckConfig{Webhooks: []hook.Webhook{{Name: "a", URL: "http://a", Events: []hook.Event{"OnSomething"}}}}},
+			err:       "unknown event \"OnSomething\"",
+		},
+		{
+			name: "webhook and notifier with the same name",
+			kubecheck: &Kubecheck{Config: &KubecheckConfig{
+				Webhooks:  []hook.Webhook{webhook("chat")},
+				Notifiers: []hook.Notifier{notify.Slack{Name: "chat", URL: "http://slack", Events: []hook.Event{"OnCheckFailed"}}},
+			}},
+			err: "webhook and notifier share the name \"chat\"",
+		},
+		{
+			name: "webhook and notifier with the same name in a group",
+			kubecheck: &Kubecheck{
+				Config: &KubecheckConfig{},
+				Groups: []Group{{
+					Name:      "core",
+					Webhooks:  []hook.Webhook{webhook("chat")},
+					Notifiers: []hook.Notifier{notify.Slack{Name: "chat", URL: "http://slack", Events: []hook.Event{"OnCheckFailed"}}},
+				}},
+			},
+			err: "group \"core\": webhook and notifier share the name \"chat\"",
+		},
+		{
+			name: "same webhook name in a group",
+			kubecheck: &Kubecheck{
+				Config: &KubecheckConfig{Webhooks: []hook.Webhook{webhook("a")}},
+				Groups: []Group{{Name: "core", Webhooks: []hook.Webhook{webhook("a")}}},
+			},
+		},
+		{
+			name: "peer timeout",
+			kubecheck: &Kubecheck{Config: &KubecheckConfig{Federation: FederationConfig{Peers: []Peer{
+				{Cluster: "eu", URL: "http://eu", Timeout: time.Minute},
+			}}}},
+			err: "must be below 30s",
+		},
+		{
+			name: "negative peer timeout",
+			kubecheck: &Kubecheck{Config: &KubecheckConfig{Federation: FederationConfig{Peers: []Peer{
+				{Cluster: "eu", URL: "http://eu", Timeout: -time.Second},
+			}}}},
+			err: "must be below 30s",
+		},
+		{
+			name: "unknown group check",
+			kubecheck: &Kubecheck{
+				Config: &KubecheckConfig{},
+				Groups: []Group{{Name: "core", Checks: []string{"missing"}}},
+			},
+			err: "group \"core\": unknown healthcheck \"missing\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.kubecheck)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/StenaIT/kubecheck/hook"
+	"github.com/StenaIT/kubecheck/notify"
+)
+
+// NotifierSpec defines the fields shared by all declarative notifiers
+type NotifierSpec struct {
+	Type   string       `json:"type"`
+	Name   string       `json:"name"`
+	Events []hook.Event `json:"events"`
+	raw    json.RawMessage
+}
+
+// Decode decodes the full notifier definition into v, rejecting unknown fields.
+// v should embed NotifierSpec to accept the shared fields.
+func (s NotifierSpec) Decode(v interface{}) error {
+	return decodeStrict(s.raw, v)
+}
+
+// NotifierBuilder creates a notifier from its declarative definition
+type NotifierBuilder func(spec NotifierSpec) (hook.Notifier, error)
+
+var notifierTypes = struct {
+	sync.RWMutex
+	builders map[string]NotifierBuilder
+}{
+	builders: make(map[string]NotifierBuilder),
+}
+
+// RegisterNotifierType makes a notifier type available to declarative configuration
+func RegisterNotifierType(name string, builder NotifierBuilder) {
+	notifierTypes.Lock()
+	defer notifierTypes.Unlock()
+	notifierTypes.builders[name] = builder
+}
+
+func init() {
+	RegisterNotifierType("slack", buildSlackNotifier)
+	RegisterNotifierType("teams", buildTeamsNotifier)
+	RegisterNotifierType("pagerduty", buildPagerDutyNotifier)
+	RegisterNotifierType("alertmanager", buildAlertmanagerNotifier)
+	RegisterNotifierType("email", buildEmailNotifier)
+	RegisterNotifierType("heartbeat", buildHeartbeatNotifier)
+	RegisterNotifierType("pushgateway", buildPushgatewayNotifier)
+	RegisterNotifierType("statsd", buildStatsDNotifier)
+}
+
+func buildNotifiers(raws []json.RawMessage) ([]hook.Notifier, error) {
+	notifiers := make([]hook.Notifier, 0, len(raws))
+	for i, raw := range raws {
+		n, err := buildNotifier(raw)
+		if err != nil {
+			return nil, fmt.Errorf("notifiers[%d]: %v", i, err)
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+func buildNotifier(raw json.RawMessage) (hook.Notifier, error) {
+	spec := NotifierSpec{}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, err
+	}
+	spec.raw = raw
+
+	if spec.Name == "" {
+		return nil, fmt.Errorf("missing name")
+	}
+
+	notifierTypes.RLock()
+	builder, ok := notifierTypes.builders[spec.Type]
+	notifierTypes.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown type \"%s\"", spec.Name, spec.Type)
+	}
+
+	n, err := builder(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", spec.Name, err)
+	}
+
+	return n, nil
+}
+
+func buildSlackNotifier(spec NotifierSpec) (hook.Notifier, error) {
+	s := struct {
+		NotifierSpec
+		URL      string `json:"url"`
+		Source   string `json:"source"`
+		Channel  string `json:"channel"`
+		Username string `json:"username"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return notify.Slack{
+		Name:     s.Name,
+		URL:      s.URL,
+		Events:   s.Events,
+		Source:   s.Source,
+		Channel:  s.Channel,
+		Username: s.Username,
+	}, nil
+}
+
+func buildTeamsNotifier(spec NotifierSpec) (hook.Notifier, error) {
+	s := struct {
+		NotifierSpec
+		URL    string `json:"url"`
+		Source string `json:"source"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return notify.Teams{
+		Name:   s.Name,
+		URL:    s.URL,
+		Events: s.Events,
+		Source: s.Source,
+	}, nil
+}
+
+func buildPagerDutyNotifier(spec NotifierSpec) (hook.Notifier, error) {
+	s := struct {
+		NotifierSpec
+		RoutingKey string `json:"routingKey"`
+		Source     string `json:"source"`
+		Severity   string `json:"severity"`
+		URL        string `json:"url"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return notify.PagerDuty{
+		Name:       s.Name,
+		RoutingKey: s.RoutingKey,
+		Events:     s.Events,
+		Source:     s.Source,
+		Severity:   s.Severity,
+		URL:        s.URL,
+	}, nil
+}
+
+func buildAlertmanagerNotifier(spec NotifierSpec) (hook.Notifier, error) {
+	s := struct {
+		NotifierSpec
+		URL            string            `json:"url"`
+		Headers        map[string]string `json:"headers"`
+		AlertName      string            `json:"alertName"`
+		Labels         map[string]string `json:"labels"`
+		Severity       string            `json:"severity"`
+		ResolveTimeout Duration          `json:"resolveTimeout"`
+		GeneratorURL   string            `json:"generatorURL"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return notify.Alertmanager{
+		Name:           s.Name,
+		URL:            s.URL,
+		Events:         s.Events,
+		Headers:        s.Headers,
+		AlertName:      s.AlertName,
+		Labels:         s.Labels,
+		Severity:       s.Severity,
+		ResolveTimeout: time.Duration(s.ResolveTimeout),
+		GeneratorURL:   s.GeneratorURL,
+	}, nil
+}
+
+func buildEmailNotifier(spec NotifierSpec) (hook.Notifier, error) {
+	s := struct {
+		NotifierSpec
+		Host      string            `json:"host"`
+		Port      int               `json:"port"`
+		TLS       string            `json:"tls"`
+		Username  string            `json:"username"`
+		Password  string            `json:"password"`
+		From      string            `json:"from"`
+		To        []string          `json:"to"`
+		Source    string            `json:"source"`
+		Tags      map[string]string `json:"tags"`
+		RateLimit Duration          `json:"rateLimit"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return notify.Email{
+		Name:      s.Name,
+		Host:      s.Host,
+		Port:      s.Port,
+		TLS:       s.TLS,
+		Username:  s.Username,
+		Password:  s.Password,
+		From:      s.From,
+		To:        s.To,
+		Events:    s.Events,
+		Source:    s.Source,
+		Tags:      s.Tags,
+		RateLimit: time.Duration(s.RateLimit),
+	}, nil
+}
+
+func buildHeartbeatNotifier(spec NotifierSpec) (hook.Notifier, error) {
+	s := struct {
+		NotifierSpec
+		URL        string `json:"url"`
+		StartURL   string `json:"startURL"`
+		SuccessURL string `json:"successURL"`
+		FailURL    string `json:"failURL"`
+		Method     string `json:"method"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	if len(s.Events) > 0 {
+		return nil, fmt.Errorf("heartbeats are pinged on OnHealthcheckStarted and OnHealthcheckCompleted and do not take events")
+	}
+
+	return notify.Heartbeat{
+		Name:       s.Name,
+		URL:        s.URL,
+		StartURL:   s.StartURL,
+		SuccessURL: s.SuccessURL,
+		FailURL:    s.FailURL,
+		Method:     s.Method,
+	}, nil
+}
+
+func buildPushgatewayNotifier(spec NotifierSpec) (hook.Notifier, error) {
+	s := struct {
+		NotifierSpec
+		URL      string            `json:"url"`
+		Job      string            `json:"job"`
+		Grouping map[string]string `json:"grouping"`
+		Headers  map[string]string `json:"headers"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	if len(s.Events) > 0 {
+		return nil, fmt.Errorf("pushgateway notifiers push on OnHealthcheckCompleted and do not take events")
+	}
+
+	return notify.Pushgateway{
+		Name:     s.Name,
+		URL:      s.URL,
+		Job:      s.Job,
+		Grouping: s.Grouping,
+		Headers:  s.Headers,
+	}, nil
+}
+
+func buildStatsDNotifier(spec NotifierSpec) (hook.Notifier, error) {
+	s := struct {
+		NotifierSpec
+		Address   string            `json:"address"`
+		Prefix    string            `json:"prefix"`
+		DogStatsD bool              `json:"dogStatsD"`
+		Tags      map[string]string `json:"tags"`
+	}{}
+	if err := spec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	if len(s.Events) > 0 {
+		return nil, fmt.Errorf("statsd notifiers send on OnHealthcheckCompleted and do not take events")
+	}
+
+	return notify.StatsD{
+		Name:      s.Name,
+		Address:   s.Address,
+		Prefix:    s.Prefix,
+		DogStatsD: s.DogStatsD,
+		Tags:      s.Tags,
+	}, nil
+}
@@ -9,37 +9,67 @@ import (
 	"github.com/StenaIT/kubecheck/checks"
 	"github.com/StenaIT/kubecheck/config"
 	"github.com/StenaIT/kubecheck/hook"
+	"github.com/StenaIT/kubecheck/logging"
+	"github.com/StenaIT/kubecheck/notify"
 	"github.com/StenaIT/kubecheck/server"
 
 	"github.com/apex/log"
-	"github.com/apex/log/handlers/text"
 )
 
 func main() {
 	kubecheck := configureKubecheck()
-	server.New(kubecheck).ListenAndServe()
+	runtime := server.NewRuntime(kubecheck)
+
+	if configFile := os.Getenv("KUBECHECK_CONFIG_FILE"); configFile != "" {
+		runtime.WatchConfigFile(configFile, 0)
+	}
+
+	if runOnce, _ := strconv.ParseBool(envOrDefault("KUBECHECK_RUN_ONCE", "false")); runOnce {
+		if err := runtime.RunOnce(); err != nil {
+			log.WithError(err).Fatal("kubecheck run failed")
+		}
+		return
+	}
+
+	if err := runtime.Run(); err != nil {
+		log.WithError(err).Fatal("kubecheck exited with an error")
+	}
 }
 
 func configureKubecheck() *config.Kubecheck {
 	debug, _ := strconv.ParseBool(envOrDefault("KUBECHECK_DEBUG", "true"))
 	logLevel := envOrDefault("KUBECHECK_LOG_LEVEL", "info")
+	logFormat := envOrDefault("KUBECHECK_LOG_FORMAT", "text")
 	k8sInClusterConfig, _ := strconv.ParseBool(envOrDefault("KUBECHECK_K8S_INCLUSTERCONFIG", "false"))
 
+	if configFile := os.Getenv("KUBECHECK_CONFIG_FILE"); configFile != "" {
+		kubecheck, err := config.LoadFile(configFile)
+		if err != nil {
+			log.WithError(err).Fatal("failed to load configuration")
+		}
+		configurePackages(kubecheck.Config, k8sInClusterConfig)
+		return kubecheck
+	}
+
 	kubecheck := &config.Kubecheck{
 		Config: &config.KubecheckConfig{
 			Debug:    debug,
 			LogLevel: logLevel,
-			Webhooks: []hook.Webhook{
-				hook.Webhook{
-					Name:   "healthchecks-io",
-					URL:    "https://hc-ping.com/b68522d5-eb89-44a9-8335-7f668f1aa691",
-					Events: []hook.Event{config.OnHealthcheckCompletedEvent},
+			Logging: config.LoggingConfig{
+				Format: logFormat,
+			},
+			Notifiers: []hook.Notifier{
+				notify.Heartbeat{
+					Name: "healthchecks-io",
+					URL:  "https://hc-ping.com/b68522d5-eb89-44a9-8335-7f668f1aa691",
 				},
+			},
+			Webhooks: []hook.Webhook{
 				// hook.Webhook{
-				// 	Name:   "slack-on-completed",
-				// 	URL:    "<SLACK_WEBHOOK_URL>",
-				// 	Data:   `{"username": "Kubecheck", "text": "Healthchecks ran to completion"}`,
-				// 	Events: []hook.Event{config.OnHealthcheckCompletedEvent},
+				// 	Name:     "slack-on-completed",
+				// 	URL:      "<SLACK_WEBHOOK_URL>",
+				// 	Template: `{"username": "Kubecheck", "text": "Healthchecks {{.Run.Status}}: {{.Run.Failed}} of {{.Run.Total}} failed{{range .Failed}}\n{{jsonEscape .Name}}: {{jsonEscape .Reason}}{{end}}"}`,
+				// 	Events:   []hook.Event{config.OnHealthcheckCompletedEvent},
 				// },
 			},
 		},
@@ -47,14 +77,19 @@ func configureKubecheck() *config.Kubecheck {
 		Router:       nil,
 	}
 
+	configurePackages(kubecheck.Config, k8sInClusterConfig)
+
+	return kubecheck
+}
+
+func configurePackages(c *config.KubecheckConfig, k8sInClusterConfig bool) {
 	checks.Configure(checks.KubernetesConfig{
 		InClusterConfig: k8sInClusterConfig,
 	})
 
-	log.SetLevelFromString(kubecheck.Config.LogLevel)
-	log.SetHandler(text.New(os.Stdout))
-
-	return kubecheck
+	if err := logging.Configure(os.Stdout, c.Logging.Format, c.LogLevel, c.Logging.Levels); err != nil {
+		log.WithError(err).Fatal("failed to configure logging")
+	}
 }
 
 func configureHealthchecks() []checks.Healthcheck {
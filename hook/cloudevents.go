@@ -0,0 +1,227 @@
+package hook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+// CloudEvents modes of the HTTP protocol binding
+const (
+	CloudEventsStructured = "structured"
+	CloudEventsBinary     = "binary"
+)
+
+// CloudEventTypePrefix prefixes the types of the CloudEvents sent by webhooks
+const CloudEventTypePrefix = "com.github.stenait.kubecheck."
+
+const (
+	cloudEventsSpecVersion   = "1.0"
+	defaultCloudEventsSource = "kubecheck"
+)
+
+// cloudEventTypes maps events to the types of the CloudEvents sent for them
+var cloudEventTypes = map[Event]string{
+	"OnHealthcheckStarted":   "run.started",
+	"OnHealthcheckCompleted": "run.completed",
+	"OnRunFailed":            "run.failed",
+	"OnCheckFailed":          "check.failed",
+	"OnCheckStillFailing":    "check.stillfailing",
+	"OnCheckRecovered":       "check.recovered",
+	"OnChecksChanged":        "checks.changed",
+}
+
+// CloudEvents makes a webhook send CloudEvents 1.0 with the payload in the data field, instead of its data or template
+type CloudEvents struct {
+	// Mode is structured, sending the whole event as application/cloudevents+json, or binary,
+	// sending the data as the body and the attributes as ce- headers. Defaults to structured.
+	Mode string
+	// Source identifies this instance as a URI reference, e.g. /clusters/prod. Defaults to kubecheck.
+	Source string
+}
+
+// cloudEvent defines a CloudEvent in the structured JSON format
+type cloudEvent struct {
+	SpecVersion     string         `json:"specversion"`
+	ID              string         `json:"id"`
+	Source          string         `json:"source"`
+	Type            string         `json:"type"`
+	Subject         string         `json:"subject,omitempty"`
+	Time            string         `json:"time"`
+	DataContentType string         `json:"datacontenttype"`
+	Data            cloudEventData `json:"data"`
+}
+
+// cloudEventData defines the data of the CloudEvents. Per-check events carry the check, other events the results of the run.
+type cloudEventData struct {
+	Run     cloudEventRun     `json:"run"`
+	Check   *cloudEventCheck  `json:"check,omitempty"`
+	Results []cloudEventCheck `json:"results,omitempty"`
+	Failed  []cloudEventCheck `json:"failed,omitempty"`
+	Changes []cloudEventCheck `json:"changes,omitempty"`
+}
+
+type cloudEventRun struct {
+	ID              string     `json:"id"`
+	Status          string     `json:"status"`
+	Total           int        `json:"total"`
+	Passed          int        `json:"passed"`
+	Failed          int        `json:"failed"`
+	Disabled        int        `json:"disabled"`
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds,omitempty"`
+}
+
+type cloudEventCheck struct {
+	Name         string                   `json:"name"`
+	Description  string                   `json:"description,omitempty"`
+	Tags         map[string]string        `json:"tags,omitempty"`
+	Severity     string                   `json:"severity,omitempty"`
+	Owner        *checks.Owner            `json:"owner,omitempty"`
+	Status       string                   `json:"status"`
+	Reason       string                   `json:"reason,omitempty"`
+	Assertions   []checks.FailedAssertion `json:"assertions,omitempty"`
+	Event        Event                    `json:"event,omitempty"`
+	FailingSince *time.Time               `json:"failingSince,omitempty"`
+}
+
+// Validate checks the mode
+func (ce CloudEvents) Validate() error {
+	switch ce.Mode {
+	case "", CloudEventsStructured, CloudEventsBinary:
+		return nil
+	}
+	return fmt.Errorf("unknown cloudEvents mode \"%s\", expected structured or binary", ce.Mode)
+}
+
+// CloudEventType returns the type of the CloudEvents sent for an event, e.g. com.github.stenait.kubecheck.check.failed
+func CloudEventType(e Event) string {
+	if t, ok := cloudEventTypes[e]; ok {
+		return CloudEventTypePrefix + t
+	}
+	return CloudEventTypePrefix + strings.ToLower(strings.TrimPrefix(string(e), "On"))
+}
+
+// cloudEvent creates the request of the webhook carrying a CloudEvent for the payload, in the mode of the webhook
+func (wh Webhook) cloudEvent(p Payload, now time.Time) (*Request, error) {
+	ce := wh.CloudEvents
+	event := cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              newEventID(),
+		Source:          ce.Source,
+		Type:            CloudEventType(p.Event),
+		Time:            now.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            newCloudEventData(p),
+	}
+	if event.Source == "" {
+		event.Source = defaultCloudEventsSource
+	}
+	if p.Check != nil {
+		event.Subject = p.Check.Name
+	}
+
+	if ce.Mode != CloudEventsBinary {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		req, err := wh.request(body)
+		if err != nil {
+			return nil, err
+		}
+		req.ContentType = "application/cloudevents+json; charset=utf-8"
+		return req, nil
+	}
+
+	body, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, err
+	}
+	req, err := wh.request(body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentType = event.DataContentType
+	req.Headers["ce-specversion"] = event.SpecVersion
+	req.Headers["ce-id"] = event.ID
+	req.Headers["ce-source"] = event.Source
+	req.Headers["ce-type"] = event.Type
+	req.Headers["ce-time"] = event.Time
+	if event.Subject != "" {
+		req.Headers["ce-subject"] = event.Subject
+	}
+	return req, nil
+}
+
+func newCloudEventData(p Payload) cloudEventData {
+	data := cloudEventData{
+		Run: cloudEventRun{
+			ID:        p.Run.ID,
+			Status:    p.Run.Status,
+			Total:     p.Run.Total,
+			Passed:    p.Run.Passed,
+			Failed:    p.Run.Failed,
+			Disabled:  p.Run.Disabled,
+			StartedAt: p.Run.StartedAt,
+		},
+	}
+	if !p.Run.FinishedAt.IsZero() {
+		finishedAt := p.Run.FinishedAt
+		data.Run.FinishedAt = &finishedAt
+		data.Run.DurationSeconds = p.Run.Duration.Seconds()
+	}
+
+	if p.Check != nil {
+		c := newCloudEventCheck(*p.Check)
+		data.Check = &c
+		return data
+	}
+
+	data.Results = newCloudEventChecks(p.Results)
+	data.Failed = newCloudEventChecks(p.Failed)
+	data.Changes = newCloudEventChecks(p.Changes)
+	return data
+}
+
+func newCloudEventChecks(results []CheckResult) []cloudEventCheck {
+	out := make([]cloudEventCheck, 0, len(results))
+	for _, cr := range results {
+		out = append(out, newCloudEventCheck(cr))
+	}
+	return out
+}
+
+func newCloudEventCheck(cr CheckResult) cloudEventCheck {
+	c := cloudEventCheck{
+		Name:        cr.Name,
+		Description: cr.Description,
+		Tags:        cr.Tags,
+		Severity:    cr.Severity,
+		Status:      cr.Status,
+		Reason:      cr.Reason,
+		Assertions:  cr.Assertions,
+		Event:       cr.Event,
+	}
+	if !cr.Owner.IsZero() {
+		owner := cr.Owner
+		c.Owner = &owner
+	}
+	if !cr.FailingSince.IsZero() {
+		failingSince := cr.FailingSince
+		c.FailingSince = &failingSince
+	}
+	return c
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
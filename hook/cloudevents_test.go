@@ -0,0 +1,199 @@
+package hook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+var cloudEventTime = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// failedCheckPayload returns the payload of a check that started failing
+func failedCheckPayload() Payload {
+	check := CheckResult{
+		Name:         "dns",
+		Tags:         map[string]string{"team": "network"},
+		Severity:     "critical",
+		Owner:        checks.Owner{Team: "network"},
+		Status:       checks.Failed,
+		Reason:       "lookup failed",
+		Assertions:   []checks.FailedAssertion{{Group: "records", Type: "Equals", Expected: "1", Actual: "0"}},
+		FailingSince: cloudEventTime,
+		Event:        "OnCheckFailed",
+	}
+	return Payload{
+		Event: "OnCheckFailed",
+		Run:   RunSummary{ID: "run-1", Status: "running", Total: 2, StartedAt: cloudEventTime},
+		Check: &check,
+	}
+}
+
+// cloudEventRequest creates the request of a webhook sending CloudEvents in the mode
+func cloudEventRequest(t *testing.T, mode string, p Payload) *Request {
+	t.Helper()
+
+	wh := Webhook{
+		Name:        "bus",
+		URL:         "http://bus",
+		Events:      []Event{p.Event},
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		CloudEvents: &CloudEvents{Mode: mode, Source: "/clusters/prod"},
+	}
+	if err := wh.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	req, err := wh.cloudEvent(p, cloudEventTime)
+	if err != nil {
+		t.Fatalf("cloudEvent() error: %v", err)
+	}
+	return req
+}
+
+// assertCheckData asserts that the data of a per-check event carries the check
+func assertCheckData(t *testing.T, data cloudEventData) {
+	t.Helper()
+
+	if data.Run.ID != "run-1" || data.Run.Status != "running" || data.Run.Total != 2 {
+		t.Errorf("run = %+v", data.Run)
+	}
+	c := data.Check
+	if c == nil {
+		t.Fatal("data has no check")
+	}
+	if c.Name != "dns" || c.Status != checks.Failed || c.Reason != "lookup failed" || c.Event != "OnCheckFailed" || c.Severity != "critical" {
+		t.Errorf("check = %+v", c)
+	}
+	if c.Tags["team"] != "network" || c.Owner == nil || c.Owner.Team != "network" {
+		t.Errorf("tags = %v, owner = %+v", c.Tags, c.Owner)
+	}
+	if len(c.Assertions) != 1 || c.Assertions[0].Actual != "0" {
+		t.Errorf("assertions = %+v", c.Assertions)
+	}
+	if c.FailingSince == nil || !c.FailingSince.Equal(cloudEventTime) {
+		t.Errorf("failingSince = %v", c.FailingSince)
+	}
+	if data.Results != nil || data.Changes != nil {
+		t.Errorf("data of a per-check event has results %+v and changes %+v", data.Results, data.Changes)
+	}
+}
+
+func TestCloudEventStructured(t *testing.T) {
+	req := cloudEventRequest(t, "", failedCheckPayload())
+
+	if req.ContentType != "application/cloudevents+json; charset=utf-8" {
+		t.Errorf("Content-Type = %s", req.ContentType)
+	}
+	if req.Headers["Authorization"] != "Bearer token" {
+		t.Errorf("headers = %v, want the headers of the webhook", req.Headers)
+	}
+
+	attributes := make(map[string]interface{})
+	if err := json.Unmarshal(req.Body, &attributes); err != nil {
+		t.Fatalf("invalid event %s: %v", req.Body, err)
+	}
+	want := map[string]string{
+		"specversion":     "1.0",
+		"source":          "/clusters/prod",
+		"type":            "com.github.stenait.kubecheck.check.failed",
+		"subject":         "dns",
+		"time":            "2020-06-01T12:00:00Z",
+		"datacontenttype": "application/json",
+	}
+	for name, value := range want {
+		if attributes[name] != value {
+			t.Errorf("%s = %v, want %s", name, attributes[name], value)
+		}
+	}
+	if id, _ := attributes["id"].(string); len(id) != 32 {
+		t.Errorf("id = %v, want a random id", attributes["id"])
+	}
+
+	event := struct {
+		Data cloudEventData `json:"data"`
+	}{}
+	if err := json.Unmarshal(req.Body, &event); err != nil {
+		t.Fatalf("invalid event %s: %v", req.Body, err)
+	}
+	assertCheckData(t, event.Data)
+}
+
+func TestCloudEventBinary(t *testing.T) {
+	req := cloudEventRequest(t, CloudEventsBinary, failedCheckPayload())
+
+	if req.ContentType != "application/json" {
+		t.Errorf("Content-Type = %s, want the datacontenttype", req.ContentType)
+	}
+	want := map[string]string{
+		"ce-specversion": "1.0",
+		"ce-source":      "/clusters/prod",
+		"ce-type":        "com.github.stenait.kubecheck.check.failed",
+		"ce-subject":     "dns",
+		"ce-time":        "2020-06-01T12:00:00Z",
+		"Authorization":  "Bearer token",
+	}
+	for name, value := range want {
+		if req.Headers[name] != value {
+			t.Errorf("%s = %s, want %s", name, req.Headers[name], value)
+		}
+	}
+	if len(req.Headers["ce-id"]) != 32 {
+		t.Errorf("ce-id = %s, want a random id", req.Headers["ce-id"])
+	}
+
+	data := cloudEventData{}
+	if err := json.Unmarshal(req.Body, &data); err != nil {
+		t.Fatalf("invalid data %s: %v", req.Body, err)
+	}
+	assertCheckData(t, data)
+}
+
+func TestCloudEventRun(t *testing.T) {
+	p := Payload{
+		Event: "OnHealthcheckCompleted",
+		Run:   RunSummary{ID: "run-1", Status: "running", Total: 2, StartedAt: cloudEventTime},
+	}
+	p.Add(CheckResult{Name: "api", Status: checks.Passed})
+	p.Add(*failedCheckPayload().Check)
+	p.Changes = []CheckResult{*failedCheckPayload().Check}
+	p.Complete(cloudEventTime.Add(2 * time.Second))
+
+	req := cloudEventRequest(t, CloudEventsBinary, p)
+	if req.Headers["ce-type"] != "com.github.stenait.kubecheck.run.completed" {
+		t.Errorf("ce-type = %s", req.Headers["ce-type"])
+	}
+	if _, ok := req.Headers["ce-subject"]; ok {
+		t.Errorf("run event has the subject %s", req.Headers["ce-subject"])
+	}
+
+	data := cloudEventData{}
+	if err := json.Unmarshal(req.Body, &data); err != nil {
+		t.Fatalf("invalid data %s: %v", req.Body, err)
+	}
+	if data.Run.Status != checks.Failed || data.Run.Passed != 1 || data.Run.Failed != 1 || data.Run.DurationSeconds != 2 || data.Run.FinishedAt == nil {
+		t.Errorf("run = %+v", data.Run)
+	}
+	if data.Check != nil || len(data.Results) != 2 || len(data.Failed) != 1 || data.Failed[0].Name != "dns" || len(data.Changes) != 1 {
+		t.Errorf("data = %+v, want the results, failed checks and changes of the run", data)
+	}
+}
+
+func TestCloudEventType(t *testing.T) {
+	tests := map[Event]string{
+		"OnCheckStillFailing": "com.github.stenait.kubecheck.check.stillfailing",
+		"OnChecksChanged":     "com.github.stenait.kubecheck.checks.changed",
+		"OnSomethingElse":     "com.github.stenait.kubecheck.somethingelse",
+	}
+	for e, want := range tests {
+		if got := CloudEventType(e); got != want {
+			t.Errorf("CloudEventType(%s) = %s, want %s", e, got, want)
+		}
+	}
+}
+
+func TestCloudEventsValidate(t *testing.T) {
+	if err := (CloudEvents{Mode: "batched"}).Validate(); err == nil {
+		t.Error("Validate() of an unknown mode succeeded")
+	}
+}
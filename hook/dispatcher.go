@@ -0,0 +1,374 @@
+package hook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/StenaIT/kubecheck/logging"
+	"github.com/StenaIT/kubecheck/redact"
+
+	"github.com/apex/log"
+)
+
+const (
+	defaultDeliveryTimeout = 10 * time.Second
+	defaultRetries         = 3
+	defaultBackoff         = time.Second
+	defaultMaxBackoff      = time.Minute
+	defaultQueueSize       = 100
+	maxRecentDeliveries    = 100
+	// maxDeadLetterExcerpt is the number of bytes of the body logged for failed deliveries
+	maxDeadLetterExcerpt = 512
+)
+
+// Delivery states
+const (
+	DeliveryQueued    = "queued"
+	DeliveryRetrying  = "retrying"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+	DeliveryDropped   = "dropped"
+)
+
+// DeliveryConfig defines how webhooks and other notifiers are delivered
+type DeliveryConfig struct {
+	// Timeout of each attempt. Defaults to 10 seconds.
+	Timeout time.Duration
+	// Retries is the number of retries after a failed attempt. Defaults to 3, negative disables retries.
+	Retries int
+	// Backoff is the delay before the first retry, doubled for every further retry. Defaults to one second.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries. Defaults to one minute.
+	MaxBackoff time.Duration
+	// QueueSize is the number of pending deliveries per notifier. Defaults to 100.
+	QueueSize int
+}
+
+// DeliveryStatus defines the state of a webhook delivery and its attempts
+type DeliveryStatus struct {
+	ID        string    `json:"id"`
+	Hook      string    `json:"hook"`
+	Type      string    `json:"type"`
+	Event     Event     `json:"event"`
+	URL       string    `json:"url"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	Attempts  []Attempt `json:"attempts"`
+}
+
+// Attempt defines a single attempt to deliver a webhook
+type Attempt struct {
+	Time       time.Time     `json:"time"`
+	Duration   time.Duration `json:"duration"`
+	StatusCode int           `json:"statusCode,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+type delivery struct {
+	status  *DeliveryStatus
+	message Message
+	logger  log.Interface
+}
+
+// Dispatcher delivers notifications asynchronously with retries.
+// Each notifier has its own queue, so deliveries to a notifier keep their order and a failing notifier does not delay others.
+type Dispatcher struct {
+	mu      sync.Mutex
+	config  DeliveryConfig
+	queues  map[string]chan *delivery
+	groups  map[groupKey]*pendingGroup
+	recent  []*DeliveryStatus
+	closed  bool
+	workers sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(config DeliveryConfig) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		config: config,
+		queues: make(map[string]chan *delivery),
+		groups: make(map[groupKey]*pendingGroup),
+		recent: make([]*DeliveryStatus, 0),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Configure replaces the delivery configuration, e.g. on reload. Queue sizes only apply to new notifiers.
+func (d *Dispatcher) Configure(config DeliveryConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.config = config
+}
+
+// Enqueue prepares and queues the messages of the notifiers subscribed to the event of the payload, logging with the logger of the context.
+// Deliveries are dropped and logged if the queue of a notifier is full or the dispatcher is closed.
+func (d *Dispatcher) Enqueue(ctx context.Context, notifiers []Notifier, p Payload) {
+	for _, n := range notifiers {
+		if !n.Subscribes(p.Event) {
+			continue
+		}
+
+		desc := n.Describe()
+		l := logging.FromContext(ctx).WithFields(log.Fields{
+			"service": "Hooks",
+			"hook":    desc.Name,
+			"type":    desc.Type,
+			"event":   p.Event,
+		})
+
+		msg, err := n.Prepare(p)
+		if err != nil {
+			l.WithError(err).Error("failed to create notification")
+			continue
+		}
+		if msg == nil {
+			continue
+		}
+
+		dl := &delivery{
+			status: &DeliveryStatus{
+				ID:        newDeliveryID(),
+				Hook:      desc.Name,
+				Type:      desc.Type,
+				Event:     p.Event,
+				URL:       msg.Destination(),
+				Status:    DeliveryQueued,
+				CreatedAt: time.Now(),
+				Attempts:  make([]Attempt, 0),
+			},
+			message: msg,
+		}
+		dl.logger = l.WithField("delivery", dl.status.ID)
+
+		d.mu.Lock()
+		d.track(dl.status)
+		if d.closed {
+			d.mu.Unlock()
+			d.deadLetter(dl, "dispatcher is closed")
+			continue
+		}
+		queue := d.queue(desc.Type + " " + desc.Name)
+		select {
+		case queue <- dl:
+			d.mu.Unlock()
+			l.Debug("queued webhook")
+		default:
+			d.mu.Unlock()
+			d.deadLetter(dl, "queue is full")
+		}
+	}
+}
+
+// queue returns the queue of a notifier, starting its worker if needed. The lock must be held.
+// Queues are keyed by the type and name of the notifier only, so that deliveries to a destination that changes,
+// like a URL referencing an environment variable, do not start another worker each time.
+func (d *Dispatcher) queue(key string) chan *delivery {
+	if q, ok := d.queues[key]; ok {
+		return q
+	}
+
+	size := d.config.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	q := make(chan *delivery, size)
+	d.queues[key] = q
+	d.workers.Add(1)
+	go d.work(q)
+	return q
+}
+
+func (d *Dispatcher) work(q chan *delivery) {
+	defer d.workers.Done()
+	for dl := range q {
+		d.deliver(dl)
+	}
+}
+
+// deliver attempts a delivery until it succeeds, the retries are exhausted or the dispatcher is cancelled
+func (d *Dispatcher) deliver(dl *delivery) {
+	d.mu.Lock()
+	config := d.config
+	d.mu.Unlock()
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultDeliveryTimeout
+	}
+	retries := config.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	backoff := config.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	maxBackoff := config.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	ctx := logging.NewContext(d.ctx, dl.logger)
+	for i := 0; ; i++ {
+		start := time.Now()
+		statusCode, err := dl.message.Send(ctx, timeout)
+		attempt := Attempt{
+			Time:       start,
+			Duration:   time.Since(start),
+			StatusCode: statusCode,
+		}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+
+		d.mu.Lock()
+		dl.status.Attempts = append(dl.status.Attempts, attempt)
+		if err == nil {
+			dl.status.Status = DeliveryDelivered
+		} else if i < retries {
+			dl.status.Status = DeliveryRetrying
+		}
+		d.mu.Unlock()
+
+		l := dl.logger.WithFields(log.Fields{
+			"attempt":  i + 1,
+			"status":   statusCode,
+			"duration": attempt.Duration,
+		})
+
+		if err == nil {
+			l.Info("delivered webhook")
+			return
+		}
+
+		if i >= retries {
+			d.deadLetter(dl, err.Error())
+			return
+		}
+
+		wait := backoff << uint(i)
+		if wait > maxBackoff || wait <= 0 {
+			wait = maxBackoff
+		}
+		l.WithError(err).Warnf("webhook delivery failed, retrying in %v", wait)
+
+		select {
+		case <-time.After(wait):
+		case <-d.ctx.Done():
+			d.deadLetter(dl, "dispatcher was cancelled while waiting to retry")
+			return
+		}
+	}
+}
+
+// deadLetter marks a delivery as failed and logs it with its size and a redacted excerpt of its body
+func (d *Dispatcher) deadLetter(dl *delivery, reason string) {
+	d.mu.Lock()
+	status := DeliveryFailed
+	if len(dl.status.Attempts) == 0 {
+		status = DeliveryDropped
+	}
+	dl.status.Status = status
+	attempts := len(dl.status.Attempts)
+	d.mu.Unlock()
+
+	dl.logger.WithFields(log.Fields{
+		"url":      dl.status.URL,
+		"attempts": attempts,
+		"reason":   reason,
+		"size":     len(dl.message.Content()),
+		"body":     excerpt(dl.message.Content()),
+	}).Errorf("webhook delivery %s", status)
+
+	if a, ok := dl.message.(Abandoner); ok {
+		a.Abandon()
+	}
+}
+
+// excerpt returns the start of a body with secrets masked, like tokens in the URLs of a Slack message
+func excerpt(body []byte) string {
+	s := string(body)
+	if json.Valid(body) {
+		if data, err := json.Marshal(redact.Default().Value(json.RawMessage(body))); err == nil {
+			s = string(data)
+		}
+	} else {
+		s = redact.Default().String(s)
+	}
+
+	if len(s) > maxDeadLetterExcerpt {
+		return s[:maxDeadLetterExcerpt] + "..."
+	}
+	return s
+}
+
+// track adds a delivery to the recent deliveries. The lock must be held.
+func (d *Dispatcher) track(s *DeliveryStatus) {
+	d.recent = append(d.recent, s)
+	if len(d.recent) > maxRecentDeliveries {
+		d.recent = append([]*DeliveryStatus(nil), d.recent[len(d.recent)-maxRecentDeliveries:]...)
+	}
+}
+
+// Recent returns the most recent deliveries, newest first
+func (d *Dispatcher) Recent() []DeliveryStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]DeliveryStatus, 0, len(d.recent))
+	for i := len(d.recent) - 1; i >= 0; i-- {
+		s := *d.recent[i]
+		s.Attempts = append([]Attempt{}, s.Attempts...)
+		out = append(out, s)
+	}
+	return out
+}
+
+// Close sends the grouped changes, stops accepting deliveries and waits for the queued deliveries, including their retries.
+// Deliveries still pending when the context is done are cancelled and logged as failed.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.flushGroups()
+
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, q := range d.queues {
+			close(q)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,268 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingServer is a stand-in webhook receiver that fails the first requests and records when each request arrived
+type failingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int32
+	times    []time.Time
+}
+
+// newFailingServer starts a receiver failing the given number of requests with 500. The server must be closed by the caller.
+func newFailingServer(failures int) *failingServer {
+	s := &failingServer{failures: int32(failures)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.times = append(s.times, time.Now())
+		s.mu.Unlock()
+
+		if atomic.AddInt32(&s.failures, -1) >= 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	return s
+}
+
+func (s *failingServer) requests() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time{}, s.times...)
+}
+
+// abandonedMessage is a stand-in message that fails every attempt and records whether it was abandoned
+type abandonedMessage struct {
+	abandoned chan struct{}
+}
+
+func (m abandonedMessage) Send(ctx context.Context, timeout time.Duration) (int, error) {
+	return http.StatusServiceUnavailable, errors.New("unavailable")
+}
+
+func (m abandonedMessage) Destination() string {
+	return "stand-in"
+}
+
+func (m abandonedMessage) Content() []byte {
+	return []byte("content")
+}
+
+func (m abandonedMessage) Abandon() {
+	close(m.abandoned)
+}
+
+// abandoningNotifier is a stand-in notifier preparing an abandonedMessage
+type abandoningNotifier struct {
+	message abandonedMessage
+}
+
+func (n abandoningNotifier) Describe() NotifierDescription {
+	return NotifierDescription{Name: "abandoning", Type: "stand-in"}
+}
+
+func (n abandoningNotifier) Subscribes(e Event) bool {
+	return true
+}
+
+func (n abandoningNotifier) Prepare(p Payload) (Message, error) {
+	return n.message, nil
+}
+
+func (n abandoningNotifier) Validate() error {
+	return nil
+}
+
+func testWebhook(url string) Webhook {
+	return Webhook{Name: "receiver", URL: url, Events: []Event{"OnHealthcheckCompleted"}, Data: "{}"}
+}
+
+var completed = Payload{Event: "OnHealthcheckCompleted", Run: RunSummary{ID: "run-1"}}
+
+// closeDispatcher closes the dispatcher, failing the test if the deliveries are not drained in time
+func closeDispatcher(t *testing.T, d *Dispatcher) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	s := newFailingServer(2)
+	defer s.Close()
+
+	d := NewDispatcher(DeliveryConfig{Retries: 3, Backoff: 20 * time.Millisecond})
+	d.Enqueue(context.Background(), []Notifier{testWebhook(s.URL)}, completed)
+	closeDispatcher(t, d)
+
+	recent := d.Recent()
+	if len(recent) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(recent))
+	}
+	dl := recent[0]
+	if dl.Status != DeliveryDelivered || len(dl.Attempts) != 3 {
+		t.Fatalf("delivery = %+v, want delivered on the third attempt", dl)
+	}
+	if dl.Attempts[0].StatusCode != http.StatusInternalServerError || dl.Attempts[0].Error == "" || dl.Attempts[2].Error != "" {
+		t.Errorf("attempts = %+v", dl.Attempts)
+	}
+	if dl.Hook != "receiver" || dl.Type != "webhook" || dl.URL != s.URL {
+		t.Errorf("delivery = %+v", dl)
+	}
+
+	// The backoff doubles with every retry
+	times := s.requests()
+	if first, second := times[1].Sub(times[0]), times[2].Sub(times[1]); first < 20*time.Millisecond || second < 40*time.Millisecond {
+		t.Errorf("retried after %s and %s, want a backoff of 20ms and 40ms", first, second)
+	}
+}
+
+func TestDispatcherMaxBackoff(t *testing.T) {
+	s := newFailingServer(3)
+	defer s.Close()
+
+	d := NewDispatcher(DeliveryConfig{Retries: 3, Backoff: 20 * time.Millisecond, MaxBackoff: 30 * time.Millisecond})
+	d.Enqueue(context.Background(), []Notifier{testWebhook(s.URL)}, completed)
+	closeDispatcher(t, d)
+
+	times := s.requests()
+	if len(times) != 4 {
+		t.Fatalf("got %d requests, want 4", len(times))
+	}
+	if last := times[3].Sub(times[2]); last < 30*time.Millisecond || last >= 80*time.Millisecond {
+		t.Errorf("retried after %s, want the maximum backoff of 30ms", last)
+	}
+}
+
+func TestDispatcherDeadLetter(t *testing.T) {
+	s := newFailingServer(10)
+	defer s.Close()
+
+	d := NewDispatcher(DeliveryConfig{Retries: 1, Backoff: time.Millisecond})
+	d.Enqueue(context.Background(), []Notifier{testWebhook(s.URL)}, completed)
+
+	abandoned := abandonedMessage{abandoned: make(chan struct{})}
+	d.Enqueue(context.Background(), []Notifier{abandoningNotifier{message: abandoned}}, completed)
+	closeDispatcher(t, d)
+
+	for _, dl := range d.Recent() {
+		if dl.Status != DeliveryFailed || len(dl.Attempts) != 2 {
+			t.Errorf("delivery = %+v, want failed after 2 attempts", dl)
+		}
+	}
+	if len(s.requests()) != 2 {
+		t.Errorf("got %d requests, want 2", len(s.requests()))
+	}
+
+	select {
+	case <-abandoned.abandoned:
+	default:
+		t.Error("the failed message was not abandoned")
+	}
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer s.Close()
+
+	d := NewDispatcher(DeliveryConfig{QueueSize: 1})
+	wh := testWebhook(s.URL)
+	d.Enqueue(context.Background(), []Notifier{wh}, completed)
+
+	// Wait for the worker to take the first delivery, so the second fills the queue and the third is dropped
+	d.mu.Lock()
+	queue := d.queues["webhook receiver"]
+	d.mu.Unlock()
+	deadline := time.Now().Add(time.Second)
+	for len(queue) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the first delivery was not taken from the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	d.Enqueue(context.Background(), []Notifier{wh}, completed)
+	d.Enqueue(context.Background(), []Notifier{wh}, completed)
+	close(release)
+	closeDispatcher(t, d)
+
+	recent := d.Recent()
+	if len(recent) != 3 || recent[0].Status != DeliveryDropped || len(recent[0].Attempts) != 0 {
+		t.Errorf("deliveries = %+v, want the newest dropped", recent)
+	}
+}
+
+func TestDispatcherQueuePerNotifier(t *testing.T) {
+	s := newFailingServer(0)
+	defer s.Close()
+
+	d := NewDispatcher(DeliveryConfig{})
+	d.Enqueue(context.Background(), []Notifier{testWebhook(s.URL)}, completed)
+	d.Enqueue(context.Background(), []Notifier{testWebhook(s.URL + "/other")}, completed)
+	other := testWebhook(s.URL)
+	other.Name = "other"
+	d.Enqueue(context.Background(), []Notifier{other}, completed)
+
+	d.mu.Lock()
+	queues := len(d.queues)
+	d.mu.Unlock()
+	if queues != 2 {
+		t.Errorf("got %d queues, want one per notifier", queues)
+	}
+	closeDispatcher(t, d)
+}
+
+func TestDispatcherClose(t *testing.T) {
+	s := newFailingServer(0)
+	defer s.Close()
+
+	d := NewDispatcher(DeliveryConfig{})
+	for i := 0; i < 5; i++ {
+		d.Enqueue(context.Background(), []Notifier{testWebhook(s.URL)}, completed)
+	}
+	closeDispatcher(t, d)
+
+	// Queued deliveries are drained before Close returns
+	if got := len(s.requests()); got != 5 {
+		t.Errorf("got %d requests, want 5", got)
+	}
+
+	d.Enqueue(context.Background(), []Notifier{testWebhook(s.URL)}, completed)
+	if recent := d.Recent(); recent[0].Status != DeliveryDropped {
+		t.Errorf("delivery after Close = %+v, want it dropped", recent[0])
+	}
+}
+
+func TestDispatcherCloseCancelsRetries(t *testing.T) {
+	s := newFailingServer(10)
+	defer s.Close()
+
+	d := NewDispatcher(DeliveryConfig{Retries: 5, Backoff: time.Hour})
+	d.Enqueue(context.Background(), []Notifier{testWebhook(s.URL)}, completed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := d.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Close() error = %v, want the deadline", err)
+	}
+
+	if recent := d.Recent(); recent[0].Status != DeliveryFailed || len(recent[0].Attempts) != 1 {
+		t.Errorf("delivery = %+v, want it failed after the first attempt", recent[0])
+	}
+}
@@ -0,0 +1,133 @@
+package hook
+
+import (
+	"context"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+const defaultGroupWait = 30 * time.Second
+
+// groupKey identifies the changes batched for a notifier of a scope, the instance or a group, by the route selecting them
+type groupKey struct {
+	scope    string
+	route    string
+	notifier string
+}
+
+// pendingGroup defines the changes batched for a notifier until its group wait has passed
+type pendingGroup struct {
+	ctx      context.Context
+	notifier Notifier
+	payload  Payload
+	index    map[string]int
+	timer    *time.Timer
+}
+
+// Group batches the check of a per-check payload for the notifiers subscribed to the grouped event.
+// Changes are batched per scope, so a notifier shared by the instance and a group gets a separate batch for each,
+// and per route, so a routed notifier gets a separate batch for each route selecting it.
+// The changes of a notifier are sent as a single payload of the grouped event once wait has passed since the first of them,
+// with the latest change of each check in Changes and Results and the failing checks in Failed.
+func (d *Dispatcher) Group(ctx context.Context, scope string, notifiers []Notifier, e Event, p Payload, wait time.Duration) {
+	if p.Check == nil {
+		return
+	}
+	if wait <= 0 {
+		wait = defaultGroupWait
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Runs have finished before the dispatcher is closed, see Runtime.Shutdown
+	if d.closed {
+		return
+	}
+
+	for _, n := range notifiers {
+		if !n.Subscribes(e) {
+			continue
+		}
+
+		desc := n.Describe()
+		for _, route := range groupRoutes(n, *p.Check) {
+			key := groupKey{scope: scope, route: route, notifier: desc.Type + " " + desc.Name}
+			d.addToGroup(ctx, key, n, e, p, wait)
+		}
+	}
+}
+
+// addToGroup adds the check of a payload to a group, starting the group if needed. The lock must be held.
+func (d *Dispatcher) addToGroup(ctx context.Context, key groupKey, n Notifier, e Event, p Payload, wait time.Duration) {
+	g, ok := d.groups[key]
+	if !ok {
+		g = &pendingGroup{
+			ctx:   ctx,
+			index: make(map[string]int),
+			payload: Payload{
+				Event:   e,
+				Results: make([]CheckResult, 0),
+				Changes: make([]CheckResult, 0),
+			},
+		}
+		d.groups[key] = g
+		g.timer = time.AfterFunc(wait, func() { d.flushGroup(key) })
+	}
+
+	g.notifier = n
+	g.payload.Run = p.Run
+	if i, ok := g.index[p.Check.Name]; ok {
+		g.payload.Changes[i] = *p.Check
+	} else {
+		g.index[p.Check.Name] = len(g.payload.Changes)
+		g.payload.Changes = append(g.payload.Changes, *p.Check)
+	}
+}
+
+// groupRoutes returns the routes selecting a notifier for a check, which is a single unnamed route for notifiers without routing
+func groupRoutes(n Notifier, cr CheckResult) []string {
+	rn, ok := n.(routedNotifier)
+	if !ok {
+		return []string{""}
+	}
+	return rn.routing.matching(cr, rn.Describe().Name)
+}
+
+// flushGroup enqueues the batched changes of a notifier
+func (d *Dispatcher) flushGroup(key groupKey) {
+	d.mu.Lock()
+	g, ok := d.groups[key]
+	delete(d.groups, key)
+	d.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	p := g.payload
+	p.Results = append([]CheckResult{}, p.Changes...)
+	p.Failed = make([]CheckResult, 0)
+	for _, cr := range p.Changes {
+		if cr.Status == checks.Failed {
+			p.Failed = append(p.Failed, cr)
+		}
+	}
+	d.Enqueue(g.ctx, []Notifier{g.notifier}, p)
+}
+
+// flushGroups enqueues all batched changes without waiting
+func (d *Dispatcher) flushGroups() {
+	d.mu.Lock()
+	keys := make([]groupKey, 0, len(d.groups))
+	for key, g := range d.groups {
+		g.timer.Stop()
+		keys = append(keys, key)
+	}
+	d.mu.Unlock()
+
+	for _, key := range keys {
+		d.flushGroup(key)
+	}
+}
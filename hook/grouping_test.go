@@ -0,0 +1,189 @@
+package hook
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+const changedEvent Event = "OnChecksChanged"
+
+// recordingNotifier is a stand-in notifier that records the payloads it sends
+type recordingNotifier struct {
+	name     string
+	payloads chan Payload
+}
+
+func newRecordingNotifier(name string) recordingNotifier {
+	return recordingNotifier{name: name, payloads: make(chan Payload, 10)}
+}
+
+func (n recordingNotifier) Describe() NotifierDescription {
+	return NotifierDescription{Name: n.name, Type: "recording"}
+}
+
+func (n recordingNotifier) Subscribes(e Event) bool {
+	return e == changedEvent
+}
+
+func (n recordingNotifier) Prepare(p Payload) (Message, error) {
+	return recordedMessage{payloads: n.payloads, payload: p}, nil
+}
+
+func (n recordingNotifier) Validate() error {
+	return nil
+}
+
+type recordedMessage struct {
+	payloads chan Payload
+	payload  Payload
+}
+
+func (m recordedMessage) Send(ctx context.Context, timeout time.Duration) (int, error) {
+	m.payloads <- m.payload
+	return 200, nil
+}
+
+func (m recordedMessage) Destination() string {
+	return "recording"
+}
+
+func (m recordedMessage) Content() []byte {
+	return nil
+}
+
+// change returns the per-check payload of a check that changed status
+func change(name, status string, event Event) Payload {
+	return Payload{Event: event, Check: &CheckResult{Name: name, Status: status, Event: event}}
+}
+
+// received returns the payloads recorded by a notifier, waiting at most for the timeout for each
+func received(n recordingNotifier, count int, timeout time.Duration) []Payload {
+	payloads := make([]Payload, 0, count)
+	for len(payloads) < count {
+		select {
+		case p := <-n.payloads:
+			payloads = append(payloads, p)
+		case <-time.After(timeout):
+			return payloads
+		}
+	}
+	return payloads
+}
+
+func changeNames(p Payload) []string {
+	names := make([]string, 0, len(p.Changes))
+	for _, cr := range p.Changes {
+		names = append(names, cr.Name)
+	}
+	return names
+}
+
+func TestGroupWait(t *testing.T) {
+	d := NewDispatcher(DeliveryConfig{})
+	defer d.Close(context.Background())
+
+	n := newRecordingNotifier("chat")
+	notifiers := []Notifier{n}
+	wait := 100 * time.Millisecond
+
+	d.Group(context.Background(), "", notifiers, changedEvent, change("api", checks.Failed, "OnCheckFailed"), wait)
+	d.Group(context.Background(), "", notifiers, changedEvent, change("dns", checks.Failed, "OnCheckFailed"), wait)
+	d.Group(context.Background(), "", notifiers, changedEvent, change("api", checks.Passed, "OnCheckRecovered"), wait)
+
+	if early := received(n, 1, wait/2); len(early) != 0 {
+		t.Fatalf("got %d payloads before the group wait", len(early))
+	}
+
+	payloads := received(n, 1, time.Second)
+	if len(payloads) != 1 {
+		t.Fatalf("got %d payloads, want 1", len(payloads))
+	}
+	p := payloads[0]
+	if p.Event != changedEvent {
+		t.Errorf("event = %s, want %s", p.Event, changedEvent)
+	}
+	if len(p.Changes) != 2 || p.Changes[0].Name != "api" || p.Changes[0].Event != "OnCheckRecovered" || p.Changes[1].Name != "dns" {
+		t.Errorf("changes = %+v, want the latest change of api and dns", p.Changes)
+	}
+	if len(p.Results) != 2 {
+		t.Errorf("got %d results, want 2", len(p.Results))
+	}
+	if len(p.Failed) != 1 || p.Failed[0].Name != "dns" {
+		t.Errorf("failed = %+v, want dns", p.Failed)
+	}
+
+	d.Group(context.Background(), "", notifiers, changedEvent, change("dns", checks.Passed, "OnCheckRecovered"), wait)
+	if payloads := received(n, 1, time.Second); len(payloads) != 1 || len(payloads[0].Changes) != 1 {
+		t.Errorf("got %+v, want a new group after the flush", payloads)
+	}
+}
+
+func TestGroupScopes(t *testing.T) {
+	d := NewDispatcher(DeliveryConfig{})
+
+	n := newRecordingNotifier("chat")
+	d.Group(context.Background(), "", []Notifier{n}, changedEvent, change("api", checks.Failed, "OnCheckFailed"), time.Hour)
+	d.Group(context.Background(), "core", []Notifier{n}, changedEvent, change("dns", checks.Failed, "OnCheckFailed"), time.Hour)
+
+	// Closing sends the pending groups
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	payloads := received(n, 2, time.Second)
+	if len(payloads) != 2 {
+		t.Fatalf("got %d payloads, want one per scope", len(payloads))
+	}
+	names := []string{changeNames(payloads[0])[0], changeNames(payloads[1])[0]}
+	sort.Strings(names)
+	if names[0] != "api" || names[1] != "dns" {
+		t.Errorf("changes = %v, want api and dns in separate groups", names)
+	}
+}
+
+func TestGroupRoutes(t *testing.T) {
+	routing := Routing{
+		Routes: []Route{
+			{Severities: []string{"critical"}, Notifiers: []string{"chat"}, Continue: true},
+			{Tags: map[string]string{"kind": "node"}, Notifiers: []string{"chat"}},
+			{Notifiers: []string{"oncall"}},
+		},
+	}
+	n := newRecordingNotifier("chat")
+	notifiers := routing.Apply([]Notifier{n})
+
+	d := NewDispatcher(DeliveryConfig{})
+	for _, p := range []Payload{
+		change("node-a", checks.Failed, "OnCheckFailed"),
+		change("node-b", checks.Failed, "OnCheckFailed"),
+		change("api", checks.Failed, "OnCheckFailed"),
+	} {
+		if p.Check.Name != "api" {
+			p.Check.Tags = map[string]string{"kind": "node"}
+		}
+		if p.Check.Name == "node-a" {
+			p.Check.Severity = "critical"
+		}
+		d.Group(context.Background(), "", notifiers, changedEvent, p, time.Hour)
+	}
+
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	payloads := received(n, 3, 200*time.Millisecond)
+	if len(payloads) != 2 {
+		t.Fatalf("got %d payloads, want one per route", len(payloads))
+	}
+	sort.Slice(payloads, func(i, j int) bool { return len(payloads[i].Changes) < len(payloads[j].Changes) })
+	if names := changeNames(payloads[0]); len(names) != 1 || names[0] != "node-a" {
+		t.Errorf("critical route changes = %v, want node-a", names)
+	}
+	if names := changeNames(payloads[1]); len(names) != 2 || names[0] != "node-a" || names[1] != "node-b" {
+		t.Errorf("node route changes = %v, want node-a and node-b", names)
+	}
+}
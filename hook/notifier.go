@@ -0,0 +1,123 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"github.com/StenaIT/kubecheck/http"
+)
+
+// NotifierDescription defines the description of a notifier
+type NotifierDescription struct {
+	Name string
+	// Type is the kind of notifier, e.g. webhook, slack, teams or pagerduty
+	Type string
+}
+
+// Notifier defines a target that is notified about hook events, like a webhook or a chat service
+type Notifier interface {
+	Describe() NotifierDescription
+	// Subscribes returns whether the notifier handles the event
+	Subscribes(e Event) bool
+	// Prepare creates the message notifying about the payload. A nil message skips the notification.
+	Prepare(p Payload) (Message, error)
+	// Validate checks the configuration of the notifier
+	Validate() error
+}
+
+// Message defines a prepared notification, which is sent again when an attempt fails
+type Message interface {
+	// Send performs a single attempt, returning the status code of the response if there is one
+	Send(ctx context.Context, timeout time.Duration) (int, error)
+	// Destination describes where the message is sent without revealing secrets, e.g. the scheme and host of a URL
+	Destination() string
+	// Content returns the content of the message, which is logged when the delivery fails
+	Content() []byte
+}
+
+// Abandoner is implemented by messages that hold on to state until they are delivered, like the recipients of an email.
+// Abandon is called once the delivery is given up, after its last attempt or when it is dropped.
+type Abandoner interface {
+	Abandon()
+}
+
+// Request defines a single HTTP request sent by a notifier
+type Request struct {
+	Method      string
+	URL         string
+	ContentType string
+	Headers     map[string]string
+	Body        []byte
+	// Secret signs the body in the SignatureHeader on each attempt if set, so retries carry a fresh timestamp
+	Secret string
+}
+
+// Notifiers returns the webhooks as notifiers
+func Notifiers(hooks []Webhook) []Notifier {
+	notifiers := make([]Notifier, 0, len(hooks))
+	for _, wh := range hooks {
+		notifiers = append(notifiers, wh)
+	}
+	return notifiers
+}
+
+// Send performs a single attempt of the request, treating responses other than 2xx as failures
+func (req *Request) Send(ctx context.Context, timeout time.Duration) (int, error) {
+	client := http.NewClient(req.URL).WithContext(ctx)
+	client.Client.Timeout = timeout
+	if req.ContentType != "" {
+		client.ContentType = req.ContentType
+	}
+	client.Headers = req.Headers
+	if req.Secret != "" {
+		client.Headers = make(map[string]string, len(req.Headers)+1)
+		for name, value := range req.Headers {
+			client.Headers[name] = value
+		}
+		client.Headers[SignatureHeader] = Sign(req.Secret, time.Now(), req.Body)
+	}
+
+	method := req.Method
+	if method == "" {
+		method = "POST"
+	}
+
+	resp, err := client.Request(method, "", bytes.NewReader(req.Body))
+	if err != nil {
+		if uerr, ok := err.(*url.Error); ok {
+			uerr.URL = displayURL(req.URL)
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Destination returns the scheme and host of the URL
+func (req *Request) Destination() string {
+	return displayURL(req.URL)
+}
+
+// Content returns the body of the request
+func (req *Request) Content() []byte {
+	return req.Body
+}
+
+// displayURL returns the scheme and host of a notifier URL, since the path of services like Slack carries a secret
+func displayURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
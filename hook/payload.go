@@ -0,0 +1,195 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+// Payload defines the data available to webhook templates
+type Payload struct {
+	Event   Event
+	Run     RunSummary
+	Results []CheckResult
+	// Failed are the results of the failed checks
+	Failed []CheckResult
+	// Check is the check that changed status for per-check events like OnCheckFailed
+	Check *CheckResult
+	// Changes are the checks that changed status so far in the run, with the per-check event each of them triggered
+	Changes []CheckResult
+}
+
+// RunSummary defines the summary of a healthcheck run
+type RunSummary struct {
+	ID string
+	// Status is running until the run has completed, then passed or failed
+	Status string
+	// Group is the group whose checks were run, empty for runs of the instance
+	Group string
+	// Partial is set for runs that left out checks, like runs of a single check or cancelled runs
+	Partial    bool
+	Total      int
+	Passed     int
+	Failed     int
+	Disabled   int
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Duration   time.Duration
+}
+
+// CheckResult defines the result of a single healthcheck in a run
+type CheckResult struct {
+	Name        string
+	Description string
+	Tags        map[string]string
+	Severity    string
+	Owner       checks.Owner
+	Status      string
+	Reason      string
+	Assertions  []checks.FailedAssertion
+	// Duration is how long the check took to execute, zero for disabled checks
+	Duration time.Duration
+	// FailingSince is when the check started failing, set for per-check events
+	FailingSince time.Time
+	// Event is the per-check event triggered by the check, set for per-check events and changes
+	Event Event
+}
+
+// NewCheckResult creates the result of a healthcheck for a payload, including its failed assertions
+func NewCheckResult(d checks.Description, r checks.Result) CheckResult {
+	cr := CheckResult{
+		Name:        d.Name,
+		Description: d.Description,
+		Tags:        d.Tags,
+		Severity:    d.Severity,
+		Owner:       d.Owner,
+		Status:      r.Status,
+		Reason:      r.Reason,
+		Assertions:  make([]checks.FailedAssertion, 0),
+	}
+	if r.Status == checks.Failed {
+		cr.Assertions = checks.FailedAssertions(r.Output)
+	}
+	return cr
+}
+
+// Add adds the result of a healthcheck to the payload and updates the run summary
+func (p *Payload) Add(cr CheckResult) {
+	p.Results = append(p.Results, cr)
+
+	switch cr.Status {
+	case checks.Passed:
+		p.Run.Passed++
+	case checks.Failed:
+		p.Run.Failed++
+		p.Failed = append(p.Failed, cr)
+	case checks.Disabled:
+		p.Run.Disabled++
+	}
+}
+
+// Complete marks the run as finished, with the status failed if any check failed and passed otherwise
+func (p *Payload) Complete(finishedAt time.Time) {
+	p.Run.FinishedAt = finishedAt
+	p.Run.Duration = finishedAt.Sub(p.Run.StartedAt)
+	p.Run.Status = checks.Passed
+	if p.Run.Failed > 0 {
+		p.Run.Status = checks.Failed
+	}
+}
+
+// Filter returns the payload with only the results, failed checks and changes for which keep returns true.
+// The run summary counts only the kept results once the run has completed.
+func (p Payload) Filter(keep func(CheckResult) bool) Payload {
+	filtered := p
+	filtered.Results = make([]CheckResult, 0)
+	filtered.Failed = make([]CheckResult, 0)
+	filtered.Changes = make([]CheckResult, 0)
+	filtered.Run.Passed, filtered.Run.Failed, filtered.Run.Disabled = 0, 0, 0
+
+	for _, cr := range p.Results {
+		if keep(cr) {
+			filtered.Add(cr)
+		}
+	}
+	for _, cr := range p.Changes {
+		if keep(cr) {
+			filtered.Changes = append(filtered.Changes, cr)
+		}
+	}
+
+	if p.Run.Status == checks.Passed || p.Run.Status == checks.Failed {
+		filtered.Run.Total = len(filtered.Results)
+		filtered.Complete(p.Run.FinishedAt)
+	}
+	return filtered
+}
+
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {"text": {{json .Run.Status}}}
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// jsonEscape escapes a string for use inside a JSON string, e.g. {"text": "Run {{jsonEscape .Run.ID}}"}
+	"jsonEscape": func(s string) string {
+		data, _ := json.Marshal(s)
+		return string(data[1 : len(data)-1])
+	},
+	// truncate shortens a string to at most n characters
+	"truncate": func(n int, s string) string {
+		r := []rune(s)
+		if n < 0 || len(r) <= n {
+			return s
+		}
+		if n <= 3 {
+			return string(r[:n])
+		}
+		return string(r[:n-3]) + "..."
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// default returns the value, or def if the value is empty like the missing tag of a check, e.g. {{default "none" .Check.Tags.team}}
+	"default": func(def string, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	// names returns the names of the results
+	"names": func(results []CheckResult) []string {
+		names := make([]string, 0, len(results))
+		for _, r := range results {
+			names = append(names, r.Name)
+		}
+		return names
+	},
+}
+
+// parseTemplate parses the template of a webhook. Missing map keys, like tags that a check does not have, render as empty values.
+func parseTemplate(wh Webhook) (*template.Template, error) {
+	return template.New(wh.Name).Funcs(templateFuncs).Option("missingkey=zero").Parse(wh.Template)
+}
+
+// Body returns the body posted by the webhook, which is the rendered template if there is one, otherwise the data
+func (wh Webhook) Body(p Payload) ([]byte, error) {
+	if wh.Template == "" {
+		return []byte(wh.Data), nil
+	}
+
+	t, err := parseTemplate(wh)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,199 @@
+package hook
+
+import (
+	"fmt"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+// recoveredEvent is routed like the failure it resolves
+const recoveredEvent Event = "OnCheckRecovered"
+
+// Routing defines which notifiers are notified about which checks.
+// Notifiers that are not named by any route or the default route are notified about all checks.
+type Routing struct {
+	Routes []Route
+	// Default names the notifiers of checks that match no route
+	Default []string
+}
+
+// Route selects the notifiers of the checks it matches. Empty conditions match all checks.
+type Route struct {
+	// Owners matches the team of the owner of a check
+	Owners []string
+	// Tags matches checks having all of these tags
+	Tags map[string]string
+	// Severities matches the severity of a check
+	Severities []string
+	// Statuses matches the status of a check: failed, passed or disabled. Recovered checks match failed,
+	// so their recovery goes to the notifiers of their failure.
+	Statuses []string
+	// Notifiers names the webhooks and notifiers of the matched checks
+	Notifiers []string
+	// Continue also evaluates the next routes after a match. Only the first matching route is used otherwise.
+	Continue bool
+}
+
+// routedNotifier narrows the payloads of a notifier to the checks routed to it
+type routedNotifier struct {
+	Notifier
+	routing Routing
+}
+
+// IsZero returns whether no routes are defined
+func (r Routing) IsZero() bool {
+	return len(r.Routes) == 0 && len(r.Default) == 0
+}
+
+// Validate checks that the routes only name known notifiers
+func (r Routing) Validate(names []string) error {
+	known := make(map[string]bool)
+	for _, name := range names {
+		known[name] = true
+	}
+
+	for i, route := range r.Routes {
+		if len(route.Notifiers) == 0 {
+			return fmt.Errorf("route %d has no notifiers", i)
+		}
+		for _, name := range route.Notifiers {
+			if !known[name] {
+				return fmt.Errorf("route %d has an unknown notifier \"%s\"", i, name)
+			}
+		}
+	}
+	for _, name := range r.Default {
+		if !known[name] {
+			return fmt.Errorf("default route has an unknown notifier \"%s\"", name)
+		}
+	}
+	return nil
+}
+
+// Apply returns the notifiers with their payloads narrowed to the checks routed to them.
+// Per-check events are only sent to the notifiers of the check, and run events to notifiers with at least one routed check.
+func (r Routing) Apply(notifiers []Notifier) []Notifier {
+	if r.IsZero() {
+		return notifiers
+	}
+
+	routed := make([]Notifier, 0, len(notifiers))
+	for _, n := range notifiers {
+		if r.routes(n.Describe().Name) {
+			n = routedNotifier{Notifier: n, routing: r}
+		}
+		routed = append(routed, n)
+	}
+	return routed
+}
+
+// Select returns the names of the notifiers of a check
+func (r Routing) Select(cr CheckResult) []string {
+	names := make([]string, 0)
+	for _, route := range r.Routes {
+		if !route.matches(cr) {
+			continue
+		}
+		names = append(names, route.Notifiers...)
+		if !route.Continue {
+			return names
+		}
+	}
+
+	if len(names) == 0 {
+		return r.Default
+	}
+	return names
+}
+
+// matching returns the routes selecting a notifier for a check, as "route <index>" or "default"
+func (r Routing) matching(cr CheckResult, name string) []string {
+	routes := make([]string, 0)
+	matched := false
+	for i, route := range r.Routes {
+		if !route.matches(cr) {
+			continue
+		}
+		matched = true
+		if containsString(route.Notifiers, name) {
+			routes = append(routes, fmt.Sprintf("route %d", i))
+		}
+		if !route.Continue {
+			break
+		}
+	}
+
+	if !matched && containsString(r.Default, name) {
+		routes = append(routes, "default")
+	}
+	return routes
+}
+
+// routes returns whether a notifier is named by a route
+func (r Routing) routes(name string) bool {
+	if containsString(r.Default, name) {
+		return true
+	}
+	for _, route := range r.Routes {
+		if containsString(route.Notifiers, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (route Route) matches(cr CheckResult) bool {
+	if len(route.Owners) > 0 && !containsString(route.Owners, cr.Owner.Team) {
+		return false
+	}
+	for k, v := range route.Tags {
+		if cr.Tags[k] != v {
+			return false
+		}
+	}
+	if len(route.Severities) > 0 && !containsString(route.Severities, cr.Severity) {
+		return false
+	}
+	if len(route.Statuses) > 0 && !containsString(route.Statuses, routedStatus(cr)) {
+		return false
+	}
+	return true
+}
+
+// routedStatus returns the status a check is routed by, which is failed for recovered checks
+func routedStatus(cr CheckResult) string {
+	if cr.Event == recoveredEvent {
+		return checks.Failed
+	}
+	return cr.Status
+}
+
+// Prepare prepares the message of the notifier for the checks routed to it, skipping payloads without any
+func (n routedNotifier) Prepare(p Payload) (Message, error) {
+	name := n.Describe().Name
+	selected := func(cr CheckResult) bool {
+		return containsString(n.routing.Select(cr), name)
+	}
+
+	if p.Check != nil {
+		if !selected(*p.Check) {
+			return nil, nil
+		}
+		return n.Notifier.Prepare(p.Filter(selected))
+	}
+
+	filtered := p.Filter(selected)
+	if len(p.Results) > 0 && len(filtered.Results) == 0 {
+		return nil, nil
+	}
+	return n.Notifier.Prepare(filtered)
+}
+
+func containsString(s []string, v string) bool {
+	for _, a := range s {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
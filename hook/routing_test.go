@@ -0,0 +1,113 @@
+package hook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+func TestRoutingSelect(t *testing.T) {
+	routing := Routing{
+		Routes: []Route{
+			{Owners: []string{"payments"}, Notifiers: []string{"payments-slack"}},
+			{Severities: []string{"critical"}, Notifiers: []string{"oncall"}, Continue: true},
+			{Tags: map[string]string{"kind": "node"}, Notifiers: []string{"platform-slack"}},
+			{Statuses: []string{checks.Failed}, Notifiers: []string{"pagerduty"}},
+		},
+		Default: []string{"platform-slack"},
+	}
+
+	tests := []struct {
+		name  string
+		check CheckResult
+		want  []string
+	}{
+		{
+			name:  "owner",
+			check: CheckResult{Owner: checks.Owner{Team: "payments"}, Severity: "critical", Status: checks.Failed},
+			want:  []string{"payments-slack"},
+		},
+		{
+			name:  "continue",
+			check: CheckResult{Severity: "critical", Tags: map[string]string{"kind": "node"}, Status: checks.Failed},
+			want:  []string{"oncall", "platform-slack"},
+		},
+		{
+			name:  "tags",
+			check: CheckResult{Tags: map[string]string{"kind": "node", "zone": "a"}, Status: checks.Passed},
+			want:  []string{"platform-slack"},
+		},
+		{
+			name:  "failed",
+			check: CheckResult{Status: checks.Failed, Event: "OnCheckFailed"},
+			want:  []string{"pagerduty"},
+		},
+		{
+			name:  "reminder",
+			check: CheckResult{Status: checks.Failed, Event: "OnCheckStillFailing"},
+			want:  []string{"pagerduty"},
+		},
+		{
+			name:  "recovered like the failure",
+			check: CheckResult{Status: checks.Passed, Event: "OnCheckRecovered"},
+			want:  []string{"pagerduty"},
+		},
+		{
+			name:  "passed",
+			check: CheckResult{Status: checks.Passed},
+			want:  []string{"platform-slack"},
+		},
+		{
+			name:  "default",
+			check: CheckResult{Status: checks.Disabled},
+			want:  []string{"platform-slack"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routing.Select(tt.check); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoutingApply(t *testing.T) {
+	routing := Routing{
+		Routes: []Route{
+			{Statuses: []string{checks.Failed}, Notifiers: []string{"pagerduty"}},
+		},
+		Default: []string{"slack"},
+	}
+	notifiers := routing.Apply(Notifiers([]Webhook{
+		{Name: "pagerduty", URL: "http://pagerduty", Events: []Event{"OnCheckFailed", "OnCheckRecovered"}},
+		{Name: "slack", URL: "http://slack", Events: []Event{"OnCheckFailed", "OnCheckRecovered"}},
+		{Name: "audit", URL: "http://audit", Events: []Event{"OnCheckFailed", "OnCheckRecovered"}},
+	}))
+
+	prepared := func(p Payload) []string {
+		names := make([]string, 0)
+		for _, n := range notifiers {
+			msg, err := n.Prepare(p)
+			if err != nil {
+				t.Fatalf("Prepare() of %s: %v", n.Describe().Name, err)
+			}
+			if msg != nil {
+				names = append(names, n.Describe().Name)
+			}
+		}
+		return names
+	}
+
+	failed := CheckResult{Name: "dns", Status: checks.Failed, Event: "OnCheckFailed"}
+	if got, want := prepared(Payload{Event: "OnCheckFailed", Check: &failed}), []string{"pagerduty", "audit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("failure sent to %v, want %v", got, want)
+	}
+
+	recovered := CheckResult{Name: "dns", Status: checks.Passed, Event: "OnCheckRecovered"}
+	if got, want := prepared(Payload{Event: "OnCheckRecovered", Check: &recovered}), []string{"pagerduty", "audit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recovery sent to %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package hook
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignatureRoundTrip(t *testing.T) {
+	body := []byte(`{"event": "OnCheckFailed"}`)
+	now := time.Now()
+	header := Sign("secret", now, body)
+
+	if !strings.HasPrefix(header, "t=") || !strings.Contains(header, ",v1=") {
+		t.Fatalf("Sign() = %s", header)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		err       string
+	}{
+		{name: "valid", secret: "secret", header: header, body: body, tolerance: time.Minute},
+		{name: "no tolerance", secret: "secret", header: Sign("secret", now.Add(-time.Hour), body), body: body},
+		{name: "wrong secret", secret: "other", header: header, body: body, err: "signature mismatch"},
+		{name: "modified body", secret: "secret", header: header, body: []byte(`{}`), err: "signature mismatch"},
+		{name: "expired", secret: "secret", header: Sign("secret", now.Add(-2*time.Minute), body), body: body, tolerance: time.Minute, err: "outside of tolerance"},
+		{name: "future", secret: "secret", header: Sign("secret", now.Add(2*time.Minute), body), body: body, tolerance: time.Minute, err: "outside of tolerance"},
+		{name: "within tolerance", secret: "secret", header: Sign("secret", now.Add(-30*time.Second), body), body: body, tolerance: time.Minute},
+		{name: "malformed", secret: "secret", header: "v1=abc", body: body, err: "malformed signature"},
+		{name: "malformed timestamp", secret: "secret", header: "t=now,v1=abc", body: body, err: "malformed signature timestamp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.header, tt.body, tt.tolerance)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("VerifySignature() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("VerifySignature() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	os.Setenv("KUBECHECK_TEST_SECRET", "pa$$word")
+	defer os.Unsetenv("KUBECHECK_TEST_SECRET")
+
+	headers := make(chan string, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		headers <- r.Header.Get(SignatureHeader)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	wh := Webhook{Name: "signed", URL: server.URL, Events: []Event{"OnCheckFailed"}, Data: `{"text": "failed"}`, Secret: "${KUBECHECK_TEST_SECRET}"}
+	msg, err := wh.Prepare(Payload{Event: "OnCheckFailed"})
+	if err != nil {
+		t.Fatalf("Prepare() error: %v", err)
+	}
+	if _, err := msg.Send(context.Background(), time.Second); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	header, body := <-headers, <-bodies
+	if err := VerifySignature("pa$$word", header, body, time.Minute); err != nil {
+		t.Errorf("VerifySignature() of %s error: %v", header, err)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("KUBECHECK_TEST_TOKEN", "token")
+	defer os.Unsetenv("KUBECHECK_TEST_TOKEN")
+	os.Unsetenv("KUBECHECK_TEST_UNSET")
+
+	tests := []struct {
+		in   string
+		want string
+		err  string
+	}{
+		{in: "Bearer ${KUBECHECK_TEST_TOKEN}", want: "Bearer token"},
+		{in: "${KUBECHECK_TEST_TOKEN}-${KUBECHECK_TEST_TOKEN}", want: "token-token"},
+		{in: "pa$$word", want: "pa$$word"},
+		{in: "$KUBECHECK_TEST_TOKEN", want: "$KUBECHECK_TEST_TOKEN"},
+		{in: "$KUBECHECK_TEST_UNSET", want: "$KUBECHECK_TEST_UNSET"},
+		{in: "${not a variable}", want: "${not a variable}"},
+		{in: "price: 5$", want: "price: 5$"},
+		{in: "${KUBECHECK_TEST_UNSET}", err: "environment variable KUBECHECK_TEST_UNSET is not set"},
+	}
+
+	for _, tt := range tests {
+		got, err := ExpandEnv(tt.in)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("ExpandEnv(%q) error = %v, want %q", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ExpandEnv(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
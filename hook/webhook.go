@@ -1,9 +1,13 @@
 package hook
 
 import (
-	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
 
-	"github.com/StenaIT/kubecheck/http"
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/logging"
 	"github.com/apex/log"
 )
 
@@ -12,26 +16,183 @@ type Event string
 
 // Webhook defines a webhook
 type Webhook struct {
-	Name   string
-	URL    string
-	Data   string
-	Events []Event
+	Name string
+	URL  string
+	Data string
+	// Template is a text/template rendered with a Payload and posted instead of Data
+	Template string
+	Events   []Event
+	// Method is the HTTP method. Defaults to POST.
+	Method string
+	// ContentType defaults to application/json
+	ContentType string
+	// Headers are added to each request. Values may reference environment variables like ${TOKEN}.
+	Headers map[string]string
+	// Secret signs the body with HMAC-SHA256 in the X-Kubecheck-Signature header if set.
+	// It may reference environment variables like ${WEBHOOK_SECRET}.
+	Secret string
+	// CloudEvents sends the payload as a CloudEvent instead of the data or template if set
+	CloudEvents *CloudEvents
 }
 
+const defaultTriggerTimeout = 5 * time.Second
+
 // TriggerWebhooks invokes webhooks matching the event
 func TriggerWebhooks(hooks []Webhook, e Event) {
-	if hooks != nil {
-		for _, hook := range hooks {
-			if contains(hook.Events, e) {
-				log.WithFields(log.Fields{
-					"hook":  hook.Name,
-					"event": e,
-				}).Info("Invoking webhook")
-				body := bytes.NewReader([]byte(hook.Data))
-				http.NewClient(hook.URL).Post("", body)
-			}
+	TriggerWebhooksContext(context.Background(), hooks, Payload{Event: e})
+}
+
+// TriggerWebhooksContext invokes webhooks matching the event of the payload, logging with the logger of the context
+func TriggerWebhooksContext(ctx context.Context, hooks []Webhook, p Payload) {
+	TriggerContext(ctx, Notifiers(hooks), p)
+}
+
+// TriggerContext synchronously notifies the notifiers subscribed to the event of the payload, logging with the logger of the context
+func TriggerContext(ctx context.Context, notifiers []Notifier, p Payload) {
+	for _, n := range notifiers {
+		if !n.Subscribes(p.Event) {
+			continue
+		}
+
+		d := n.Describe()
+		l := logging.FromContext(ctx).WithFields(log.Fields{
+			"service": "Hooks",
+			"hook":    d.Name,
+			"type":    d.Type,
+			"event":   p.Event,
+		})
+
+		msg, err := n.Prepare(p)
+		if err != nil {
+			l.WithError(err).Error("failed to create notification")
+			continue
+		}
+		if msg == nil {
+			continue
+		}
+
+		l.Info("Invoking webhook")
+		if _, err := msg.Send(ctx, defaultTriggerTimeout); err != nil {
+			l.WithError(err).Warn("webhook delivery failed")
+		}
+	}
+}
+
+// Describe returns the description of the webhook
+func (wh Webhook) Describe() NotifierDescription {
+	return NotifierDescription{Name: wh.Name, Type: "webhook"}
+}
+
+// Subscribes returns whether the webhook is triggered by the event
+func (wh Webhook) Subscribes(e Event) bool {
+	return contains(wh.Events, e)
+}
+
+// Prepare creates the request of the webhook with its method, content type, headers and signature, carrying a CloudEvent if configured
+func (wh Webhook) Prepare(p Payload) (Message, error) {
+	if wh.CloudEvents != nil {
+		req, err := wh.cloudEvent(p, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return req, nil
+	}
+
+	body, err := wh.Body(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template: %v", err)
+	}
+	req, err := wh.request(body)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Validate checks the method, that referenced environment variables are set, the CloudEvents mode and that the template can be parsed and rendered
+func (wh Webhook) Validate() error {
+	switch strings.ToUpper(wh.Method) {
+	case "", "POST", "PUT", "PATCH", "GET", "DELETE":
+	default:
+		return fmt.Errorf("webhook \"%s\" has an unsupported method \"%s\"", wh.Name, wh.Method)
+	}
+
+	if _, err := wh.request(nil); err != nil {
+		return fmt.Errorf("webhook \"%s\": %v", wh.Name, err)
+	}
+
+	if wh.CloudEvents != nil {
+		if wh.Template != "" {
+			return fmt.Errorf("webhook \"%s\" cannot have both a template and cloudEvents", wh.Name)
+		}
+		if err := wh.CloudEvents.Validate(); err != nil {
+			return fmt.Errorf("webhook \"%s\": %v", wh.Name, err)
 		}
 	}
+
+	if wh.Template == "" {
+		return nil
+	}
+
+	if _, err := parseTemplate(wh); err != nil {
+		return fmt.Errorf("webhook \"%s\" has an invalid template: %v", wh.Name, err)
+	}
+
+	sample := Payload{
+		Event: Event("OnHealthcheckCompleted"),
+		Run:   RunSummary{ID: "sample", Total: 1, StartedAt: time.Now()},
+	}
+	check := CheckResult{
+		Name:        "sample",
+		Description: "sample check",
+		Tags:        map[string]string{"team": "sample", "env": "sample"},
+		Severity:    "critical",
+		Owner:       checks.Owner{Team: "sample", Contact: "sample@example.com", RunbookURL: "https://example.com/runbook"},
+		Status:      checks.Failed,
+		Reason:      "sample failure",
+		Assertions: []checks.FailedAssertion{
+			{Group: "sample", Type: "Equals", Expected: "1", Actual: "2"},
+		},
+		FailingSince: time.Now(),
+		Event:        Event("OnCheckFailed"),
+	}
+	sample.Add(check)
+	sample.Check = &check
+	sample.Changes = []CheckResult{check}
+	sample.Complete(time.Now())
+
+	if _, err := wh.Body(sample); err != nil {
+		return fmt.Errorf("webhook \"%s\" has an invalid template: %v", wh.Name, err)
+	}
+	return nil
+}
+
+func (wh Webhook) request(body []byte) (*Request, error) {
+	req := &Request{
+		Method:      strings.ToUpper(wh.Method),
+		URL:         wh.URL,
+		ContentType: wh.ContentType,
+		Headers:     make(map[string]string),
+		Body:        body,
+	}
+
+	for name, value := range wh.Headers {
+		v, err := ExpandEnv(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %v", name, err)
+		}
+		req.Headers[name] = v
+	}
+
+	if wh.Secret != "" {
+		secret, err := ExpandEnv(wh.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("secret: %v", err)
+		}
+		req.Secret = secret
+	}
+
+	return req, nil
 }
 
 func contains(s []Event, e Event) bool {
@@ -0,0 +1,58 @@
+package hook
+
+import (
+	"testing"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+func TestWebhookTemplateTags(t *testing.T) {
+	wh := Webhook{
+		Name:     "chat",
+		URL:      "http://chat",
+		Events:   []Event{"OnCheckFailed"},
+		Template: `{"team": "{{.Check.Tags.team}}", "owner": "{{.Check.Tags.owner | default "platform"}}", "severity": "{{.Check.Severity | default "unknown"}}"}`,
+	}
+	if err := wh.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		check CheckResult
+		want  string
+	}{
+		{
+			name:  "tagged",
+			check: CheckResult{Name: "dns", Status: checks.Failed, Severity: "critical", Tags: map[string]string{"team": "network", "owner": "sre"}},
+			want:  `{"team": "network", "owner": "sre", "severity": "critical"}`,
+		},
+		{
+			name:  "untagged",
+			check: CheckResult{Name: "dns", Status: checks.Failed},
+			want:  `{"team": "", "owner": "platform", "severity": "unknown"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := tt.check
+			body, err := wh.Body(Payload{Event: "OnCheckFailed", Check: &check})
+			if err != nil {
+				t.Fatalf("Body() error: %v", err)
+			}
+			if string(body) != tt.want {
+				t.Errorf("Body() = %s, want %s", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookTemplateInvalid(t *testing.T) {
+	for _, template := range []string{`{{.Run.Status`, `{{.Check.Unknown}}`} {
+		wh := Webhook{Name: "chat", URL: "http://chat", Events: []Event{"OnCheckFailed"}, Template: template}
+		if err := wh.Validate(); err == nil {
+			t.Errorf("Validate() of %s succeeded, want an error", template)
+		}
+	}
+}
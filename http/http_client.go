@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"crypto/tls"
 	"io"
 	"net/http"
@@ -9,6 +10,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/StenaIT/kubecheck/logging"
+
 	"github.com/apex/log"
 )
 
@@ -18,7 +21,10 @@ type Client struct {
 	Port        int
 	Scheme      string
 	ContentType string
-	Client      *http.Client
+	// Headers are added to every request
+	Headers map[string]string
+	Client  *http.Client
+	ctx     context.Context
 }
 
 // NewClient creates a new HTTP client
@@ -28,6 +34,7 @@ func NewClient(baseURL string) *Client {
 		log.WithFields(log.Fields{
 			"service": "HTTP-Client",
 		}).WithError(err).Error("failed to create HTTP client")
+		u = &url.URL{}
 	}
 
 	port, err := strconv.Atoi(u.Port())
@@ -53,23 +60,48 @@ func NewClient(baseURL string) *Client {
 	}
 }
 
+// WithContext returns a copy of the client whose requests are bound to the context and logged with its logger
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// Request performs a HTTP request with the given method
+func (c *Client) Request(method string, path string, body io.Reader) (*http.Response, error) {
+	return c.request(method, path, body)
+}
+
 func (c *Client) request(method string, path string, requestBody io.Reader) (*http.Response, error) {
 	url := c.BaseURL + path
 	if strings.HasPrefix(path, "http") {
 		url = path
 	}
-	req, _ := http.NewRequest(method, url, requestBody)
-	req.Header.Set("Content-Type", c.ContentType)
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req, err := http.NewRequest(method, url, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if c.ContentType != "" {
+		req.Header.Set("Content-Type", c.ContentType)
+	}
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
 
-	log.WithFields(log.Fields{
+	l := logging.FromContext(ctx).WithFields(log.Fields{
 		"service": "HTTP-Client",
-	}).Debugf("HTTP %s %s", method, CleanURL(url))
+	})
+	l.Debugf("HTTP %s %s", method, CleanURL(url))
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"service": "HTTP-Client",
-		}).Debugf("error: %v", err)
+		l.Debugf("error: %v", err)
 		return nil, err
 	}
 
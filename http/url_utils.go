@@ -1,16 +1,10 @@
 package http
 
 import (
-	"fmt"
-	"net/url"
-	"strings"
+	"github.com/StenaIT/kubecheck/redact"
 )
 
-// CleanURL removes basic auth credentials from the URL
+// CleanURL removes basic auth credentials and sensitive query parameters from the URL
 func CleanURL(u string) string {
-	uri, _ := url.Parse(u)
-	p, _ := uri.User.Password()
-	pf := fmt.Sprintf(":%s@", p)
-	u = strings.Replace(u, pf, ":****@", -1)
-	return u
+	return redact.Default().String(u)
 }
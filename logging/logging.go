@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/json"
+	"github.com/apex/log/handlers/logfmt"
+	"github.com/apex/log/handlers/text"
+)
+
+// Log formats
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the logger
+func NewContext(ctx context.Context, l log.Interface) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of the context, or the default logger if it has none
+func FromContext(ctx context.Context) log.Interface {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(log.Interface); ok {
+			return l
+		}
+	}
+	return log.Log
+}
+
+// NewHandler creates a log handler writing in the given format, which is text if empty
+func NewHandler(w io.Writer, format string) (log.Handler, error) {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return text.New(w), nil
+	case FormatJSON:
+		return json.New(w), nil
+	case FormatLogfmt:
+		return logfmt.New(w), nil
+	default:
+		return nil, fmt.Errorf("unknown log format \"%s\", expected text, json or logfmt", format)
+	}
+}
+
+// ParseLevels parses the default level and the levels of subsystems
+func ParseLevels(level string, levels map[string]string) (log.Level, map[string]log.Level, error) {
+	defaultLevel := log.InfoLevel
+	if level != "" {
+		l, err := log.ParseLevel(level)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid log level \"%s\"", level)
+		}
+		defaultLevel = l
+	}
+
+	subsystems := make(map[string]log.Level)
+	for service, level := range levels {
+		l, err := log.ParseLevel(level)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid log level \"%s\" for \"%s\"", level, service)
+		}
+		subsystems[normalize(service)] = l
+	}
+
+	return defaultLevel, subsystems, nil
+}
+
+// Configure sets up the default logger to write in the given format. Entries are filtered by the level of their
+// subsystem, identified by the "service" field like "HTTP-Client" or "Hooks", and by the default level otherwise.
+func Configure(w io.Writer, format string, level string, levels map[string]string) error {
+	handler, err := NewHandler(w, format)
+	if err != nil {
+		return err
+	}
+
+	defaultLevel, subsystems, err := ParseLevels(level, levels)
+	if err != nil {
+		return err
+	}
+
+	min := defaultLevel
+	for _, l := range subsystems {
+		if l < min {
+			min = l
+		}
+	}
+
+	log.SetHandler(&levelHandler{
+		handler: handler,
+		level:   defaultLevel,
+		levels:  subsystems,
+	})
+	log.SetLevel(min)
+
+	return nil
+}
+
+// levelHandler drops entries below the level of their subsystem
+type levelHandler struct {
+	handler log.Handler
+	level   log.Level
+	levels  map[string]log.Level
+}
+
+func (h *levelHandler) HandleLog(e *log.Entry) error {
+	level := h.level
+	if service, ok := e.Fields["service"].(string); ok {
+		if l, ok := h.levels[normalize(service)]; ok {
+			level = l
+		}
+	}
+
+	if e.Level < level {
+		return nil
+	}
+	return h.handler.HandleLog(e)
+}
+
+func normalize(service string) string {
+	return strings.Replace(strings.ToLower(service), "-", "", -1)
+}
@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+const (
+	defaultAlertName      = "KubecheckFailed"
+	defaultResolveTimeout = 5 * time.Minute
+)
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Alertmanager posts alerts for failing checks to the Prometheus Alertmanager API v2.
+// Failing checks fire an alert that ends after ResolveTimeout unless it is refreshed, and recovered checks resolve it.
+// Run events refresh the alerts of all failed checks, so subscribing to OnHealthcheckCompleted keeps them firing.
+type Alertmanager struct {
+	Name string
+	// URL of Alertmanager, e.g. http://alertmanager:9093. It may reference environment variables.
+	URL    string
+	Events []hook.Event
+	// Headers are added to each request, e.g. for authentication. Values may reference environment variables.
+	Headers map[string]string
+	// AlertName is the alertname label. Defaults to KubecheckFailed.
+	AlertName string
+	// Labels are added to every alert, e.g. the cluster name
+	Labels map[string]string
+	// Severity is the severity label of checks without a severity
+	Severity string
+	// ResolveTimeout is how long an alert keeps firing without being refreshed. Defaults to 5 minutes.
+	ResolveTimeout time.Duration
+	// GeneratorURL links alerts to this instance, e.g. https://kubecheck.mydomain.io/checks/
+	GeneratorURL string
+}
+
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     string            `json:"startsAt,omitempty"`
+	EndsAt       string            `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Describe returns the description of the notifier
+func (am Alertmanager) Describe() hook.NotifierDescription {
+	return hook.NotifierDescription{Name: am.Name, Type: "alertmanager"}
+}
+
+// Subscribes returns whether the notifier handles the event
+func (am Alertmanager) Subscribes(e hook.Event) bool {
+	return subscribes(am.Events, e)
+}
+
+// Validate checks that the notifier has a name, url, events and valid labels
+func (am Alertmanager) Validate() error {
+	if err := validate("alertmanager", am.Name, am.URL, am.Events); err != nil {
+		return err
+	}
+	for name, value := range am.Headers {
+		if _, err := hook.ExpandEnv(value); err != nil {
+			return fmt.Errorf("alertmanager notifier \"%s\": header %s: %v", am.Name, name, err)
+		}
+	}
+	for name := range am.Labels {
+		if labelName(name) != name {
+			return fmt.Errorf("alertmanager notifier \"%s\" has an invalid label name \"%s\"", am.Name, name)
+		}
+	}
+	if am.ResolveTimeout < 0 {
+		return fmt.Errorf("alertmanager notifier \"%s\" has a negative resolve timeout", am.Name)
+	}
+	return nil
+}
+
+// Prepare creates the alerts for the payload. Per-check events fire or resolve the alert of the check,
+// run and grouped events refresh the alerts of the failed checks and resolve those of recovered checks. Payloads without alerts are skipped.
+func (am Alertmanager) Prepare(p hook.Payload) (hook.Message, error) {
+	now := time.Now()
+	timeout := am.ResolveTimeout
+	if timeout == 0 {
+		timeout = defaultResolveTimeout
+	}
+
+	alerts := make([]alertmanagerAlert, 0)
+	if c := p.Check; c != nil {
+		if failed(p) {
+			alerts = append(alerts, am.alert(*c, now.Add(timeout)))
+		} else {
+			alerts = append(alerts, am.alert(*c, now))
+		}
+	} else {
+		for _, c := range p.Failed {
+			alerts = append(alerts, am.alert(c, now.Add(timeout)))
+		}
+		for _, c := range p.Changes {
+			if c.Status == checks.Passed {
+				alerts = append(alerts, am.alert(c, now))
+			}
+		}
+	}
+
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+
+	url, err := hook.ExpandEnv(am.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string)
+	for name, value := range am.Headers {
+		v, err := hook.ExpandEnv(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %v", name, err)
+		}
+		headers[name] = v
+	}
+
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hook.Request{
+		Method:      "POST",
+		URL:         strings.TrimSuffix(url, "/") + "/api/v2/alerts",
+		ContentType: "application/json",
+		Headers:     headers,
+		Body:        body,
+	}, nil
+}
+
+// alert creates the alert of a check ending at endsAt, which resolves it if it is not in the future
+func (am Alertmanager) alert(c hook.CheckResult, endsAt time.Time) alertmanagerAlert {
+	labels := make(map[string]string)
+	for name, value := range c.Tags {
+		labels[labelName(name)] = value
+	}
+	for name, value := range am.Labels {
+		labels[name] = value
+	}
+
+	alertName := am.AlertName
+	if alertName == "" {
+		alertName = defaultAlertName
+	}
+	labels["alertname"] = alertName
+	labels["check"] = c.Name
+	if c.Owner.Team != "" {
+		labels["team"] = c.Owner.Team
+	}
+
+	severity := c.Severity
+	if severity == "" {
+		severity = am.Severity
+	}
+	if severity != "" {
+		labels["severity"] = severity
+	}
+
+	annotations := map[string]string{
+		"summary": fmt.Sprintf("Healthcheck %s is failing", c.Name),
+	}
+	if c.Description != "" {
+		annotations["description"] = c.Description
+	}
+	if c.Reason != "" {
+		annotations["reason"] = c.Reason
+	}
+	if c.Owner.RunbookURL != "" {
+		annotations["runbook_url"] = c.Owner.RunbookURL
+	}
+	if c.Owner.Contact != "" {
+		annotations["contact"] = c.Owner.Contact
+	}
+	if len(c.Assertions) > 0 {
+		lines := make([]string, 0, len(c.Assertions))
+		for _, a := range c.Assertions {
+			lines = append(lines, assertion(a))
+		}
+		annotations["assertions"] = strings.Join(lines, "\n")
+	}
+
+	alert := alertmanagerAlert{
+		Labels:       labels,
+		Annotations:  annotations,
+		EndsAt:       endsAt.UTC().Format(time.RFC3339),
+		GeneratorURL: am.GeneratorURL,
+	}
+	if !c.FailingSince.IsZero() {
+		alert.StartsAt = c.FailingSince.UTC().Format(time.RFC3339)
+	}
+	return alert
+}
+
+// labelName replaces characters that are not valid in Prometheus label names
+func labelName(name string) string {
+	name = invalidLabelChars.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+func TestAlertmanagerFiresAndResolves(t *testing.T) {
+	server, requests := newReceiver(t)
+	defer server.Close()
+
+	am := Alertmanager{
+		Name:         "alertmanager",
+		URL:          server.URL + "/",
+		Events:       []hook.Event{"OnCheckFailed", "OnCheckRecovered"},
+		Headers:      map[string]string{"Authorization": "Bearer token"},
+		Labels:       map[string]string{"cluster": "prod"},
+		GeneratorURL: "https://kubecheck.example.com/checks/",
+	}
+
+	before := time.Now()
+	r := send(t, am, checkPayload(failingCheck()), requests)
+	if r.path != "/api/v2/alerts" {
+		t.Errorf("path = %s, want /api/v2/alerts", r.path)
+	}
+	if got := r.header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	var firing []alertmanagerAlert
+	decode(t, r.body, &firing)
+	if len(firing) != 1 {
+		t.Fatalf("alerts = %s, want one", r.body)
+	}
+	alert := firing[0]
+	wantLabels := map[string]string{"alertname": "KubecheckFailed", "check": "dns", "kind": "network", "cluster": "prod", "team": "platform", "severity": "critical"}
+	for name, value := range wantLabels {
+		if alert.Labels[name] != value {
+			t.Errorf("label %s = %q, want %q", name, alert.Labels[name], value)
+		}
+	}
+	if alert.Annotations["reason"] != "lookup failed" || alert.Annotations["runbook_url"] != "https://runbooks.example.com/dns" || alert.Annotations["assertions"] != "records Equals: expected 1, got 0" {
+		t.Errorf("annotations = %v", alert.Annotations)
+	}
+	if alert.StartsAt != "2020-06-01T11:50:00Z" || alert.GeneratorURL != am.GeneratorURL {
+		t.Errorf("alert = %+v", alert)
+	}
+	if endsAt, err := time.Parse(time.RFC3339, alert.EndsAt); err != nil || endsAt.Before(before.Add(defaultResolveTimeout-time.Second)) {
+		t.Errorf("endsAt = %s, want the resolve timeout from now", alert.EndsAt)
+	}
+
+	r = send(t, am, checkPayload(recoveredCheck()), requests)
+
+	var resolved []alertmanagerAlert
+	decode(t, r.body, &resolved)
+	if len(resolved) != 1 || resolved[0].Labels["check"] != "dns" {
+		t.Fatalf("alerts = %s, want the alert of dns", r.body)
+	}
+	if endsAt, err := time.Parse(time.RFC3339, resolved[0].EndsAt); err != nil || endsAt.After(time.Now()) {
+		t.Errorf("endsAt = %s, want the alert to be resolved", resolved[0].EndsAt)
+	}
+}
+
+func TestAlertmanagerSkipsPassedRuns(t *testing.T) {
+	am := Alertmanager{Name: "alertmanager", URL: "http://alertmanager:9093", Events: []hook.Event{completedEvent}}
+	p := hook.Payload{Event: completedEvent, Run: hook.RunSummary{ID: "run-1", Status: "running"}}
+	p.Complete(testStart)
+	skipped(t, am, p)
+}
@@ -0,0 +1,520 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+const (
+	defaultSMTPPort       = 587
+	defaultEmailRateLimit = 15 * time.Minute
+	// maxPendingEmailChanges bounds the changes kept for a recipient that is not emailed, e.g. because the SMTP server is down
+	maxPendingEmailChanges = 100
+)
+
+// TLS modes of the email notifier
+const (
+	EmailStartTLS = "starttls"
+	EmailTLS      = "tls"
+	EmailNoTLS    = "none"
+)
+
+// Email sends a digest of the checks that changed status in a run, and of all failing checks, with SMTP.
+// Subscribe it to OnHealthcheckCompleted to get one email per run with changes, or to per-check events to get one email per change.
+type Email struct {
+	Name string
+	// Host of the SMTP server
+	Host string
+	// Port of the SMTP server. Defaults to 587.
+	Port int
+	// TLS is starttls, tls for implicit TLS like on port 465, or none. Defaults to starttls, which fails if the server does not support it.
+	TLS string
+	// Username and Password authenticate with PLAIN auth if set. The password may reference environment variables like ${SMTP_PASSWORD}.
+	Username string
+	Password string
+	From     string
+	To       []string
+	Events   []hook.Event
+	// Source identifies this instance in emails, e.g. the cluster name. Defaults to kubecheck.
+	Source string
+	// Tags limits the email to checks having all of these tags
+	Tags map[string]string
+	// RateLimit is the minimum time between two emails to a recipient. Changes in between are sent with the next email. Defaults to 15 minutes.
+	RateLimit time.Duration
+}
+
+// emailDigest defines the data available to the email templates
+type emailDigest struct {
+	Title   string
+	Run     hook.RunSummary
+	Changes []hook.CheckResult
+	Failing []hook.CheckResult
+}
+
+// emailRecipients tracks when each recipient was last emailed, the email being sent to it and the changes not emailed to it yet,
+// across reloads of the configuration. Recipients are tracked per notifier, and separately for the instance and each group.
+var emailRecipients = struct {
+	sync.Mutex
+	sent    map[string]time.Time
+	sending map[string]emailSending
+	pending map[string][]pendingEmailChange
+	seq     uint64
+}{
+	sent:    make(map[string]time.Time),
+	sending: make(map[string]emailSending),
+	pending: make(map[string][]pendingEmailChange),
+}
+
+// emailSending defines an email being sent to a recipient, including its retries. It stops holding back other emails after the rate limit,
+// in case it is never delivered or abandoned.
+type emailSending struct {
+	seq   uint64
+	since time.Time
+}
+
+// pendingEmailChange defines a change that is not emailed to a recipient yet. The sequence orders the changes of all recipients.
+type pendingEmailChange struct {
+	seq    uint64
+	change hook.CheckResult
+}
+
+var emailFuncs = map[string]interface{}{
+	"change":    change,
+	"assertion": assertion,
+	"owner":     owner,
+	"time": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+}
+
+var emailText = template.Must(template.New("text").Funcs(emailFuncs).Parse(`{{.Title}}
+{{if .Changes}}
+Changes:
+{{range .Changes}}
+- {{.Name}}: {{change .}}{{if .Reason}}
+  {{.Reason}}{{end}}{{with owner .Owner}}
+  {{.}}{{end}}{{range .Assertions}}
+  * {{assertion .}}{{end}}
+{{end}}{{end}}{{if .Failing}}
+Failing checks:
+{{range .Failing}}
+- {{.Name}}{{if .Reason}}: {{.Reason}}{{end}}{{end}}
+{{else}}
+All checks are passing.
+{{end}}
+Run {{.Run.ID}} started at {{time .Run.StartedAt}}
+`))
+
+var emailHTML = htmltemplate.Must(htmltemplate.New("html").Funcs(emailFuncs).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>{{.Title}}</h2>
+{{if .Changes}}<h3>Changes</h3>
+<table cellpadding="4" style="border-collapse: collapse">
+<tr><th align="left">Check</th><th align="left">Change</th><th align="left">Reason</th></tr>
+{{range .Changes}}<tr>
+<td><b>{{.Name}}</b></td>
+<td style="color: {{if eq .Status "failed"}}#c0392b{{else}}#27ae60{{end}}">{{change .}}</td>
+<td>{{.Reason}}{{with .Owner}}{{if or .Team .Contact}}<br><i>Owner: {{.Team}}{{if and .Team .Contact}}, {{end}}{{.Contact}}</i>{{end}}{{if .RunbookURL}}<br><a href="{{.RunbookURL}}">Runbook</a>{{end}}{{end}}{{if .Assertions}}<ul>{{range .Assertions}}<li>{{assertion .}}</li>{{end}}</ul>{{end}}</td>
+</tr>
+{{end}}</table>
+{{end}}{{if .Failing}}<h3>Failing checks</h3>
+<ul>
+{{range .Failing}}<li><b>{{.Name}}</b>{{if .Reason}}: {{.Reason}}{{end}}</li>
+{{end}}</ul>
+{{else}}<p>All checks are passing.</p>
+{{end}}<p style="color: #7f8c8d">Run {{.Run.ID}} started at {{time .Run.StartedAt}}</p>
+</body>
+</html>
+`))
+
+// Describe returns the description of the notifier
+func (e Email) Describe() hook.NotifierDescription {
+	return hook.NotifierDescription{Name: e.Name, Type: "email"}
+}
+
+// Subscribes returns whether the notifier handles the event
+func (e Email) Subscribes(ev hook.Event) bool {
+	return subscribes(e.Events, ev)
+}
+
+// Validate checks the server, TLS mode, addresses and events of the notifier
+func (e Email) Validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("email notifier has no name")
+	}
+	if e.Host == "" {
+		return fmt.Errorf("email notifier \"%s\" has no host", e.Name)
+	}
+	switch e.TLS {
+	case "", EmailStartTLS, EmailTLS, EmailNoTLS:
+	default:
+		return fmt.Errorf("email notifier \"%s\" has an unknown tls mode \"%s\"", e.Name, e.TLS)
+	}
+	if _, err := hook.ExpandEnv(e.Password); err != nil {
+		return fmt.Errorf("email notifier \"%s\": password: %v", e.Name, err)
+	}
+	if _, err := mail.ParseAddress(e.From); err != nil {
+		return fmt.Errorf("email notifier \"%s\" has an invalid from address: %v", e.Name, err)
+	}
+	if len(e.To) == 0 {
+		return fmt.Errorf("email notifier \"%s\" has no recipients", e.Name)
+	}
+	for _, to := range e.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("email notifier \"%s\" has an invalid recipient \"%s\": %v", e.Name, to, err)
+		}
+	}
+	if len(e.Events) == 0 {
+		return fmt.Errorf("email notifier \"%s\" has no events", e.Name)
+	}
+	return nil
+}
+
+// Prepare creates the digest email for the payload, with the changes of matching checks that were not emailed yet.
+// Recipients that are rate limited are skipped and get the changes with their next email.
+func (e Email) Prepare(p hook.Payload) (hook.Message, error) {
+	changes := p.Changes
+	if p.Check != nil {
+		changes = []hook.CheckResult{*p.Check}
+	}
+
+	to, changes, seq := e.pending(p.Run.Group, time.Now(), e.matching(changes))
+	if len(to) == 0 || len(changes) == 0 {
+		return nil, nil
+	}
+
+	password, err := hook.ExpandEnv(e.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := emailDigest{
+		Run:     p.Run,
+		Changes: changes,
+		Failing: e.matching(p.Failed),
+	}
+	digest.Title = changesTitle(sourceOrDefault(e.Source), changes)
+
+	data, err := e.compose(to, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &emailMessage{
+		email:    e,
+		group:    p.Run.Group,
+		password: password,
+		to:       to,
+		seq:      seq,
+		data:     data,
+	}, nil
+}
+
+// matching returns the results of checks having all the tags of the notifier
+func (e Email) matching(results []hook.CheckResult) []hook.CheckResult {
+	out := make([]hook.CheckResult, 0, len(results))
+	for _, r := range results {
+		match := true
+		for k, v := range e.Tags {
+			if r.Tags[k] != v {
+				match = false
+			}
+		}
+		if match {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// pending records the changes as not emailed to any recipient of the group yet, and returns the recipients that have not been emailed
+// within the rate limit and are not being emailed, the changes not emailed to them in order, and the sequence of the latest
+// of these changes. The recipients are reserved until the email is delivered or abandoned.
+func (e Email) pending(group string, now time.Time, changes []hook.CheckResult) ([]string, []hook.CheckResult, uint64) {
+	limit := e.RateLimit
+	if limit == 0 {
+		limit = defaultEmailRateLimit
+	}
+
+	emailRecipients.Lock()
+	defer emailRecipients.Unlock()
+
+	added := make([]pendingEmailChange, 0, len(changes))
+	for _, cr := range changes {
+		emailRecipients.seq++
+		added = append(added, pendingEmailChange{seq: emailRecipients.seq, change: cr})
+	}
+
+	to := make([]string, 0, len(e.To))
+	included := make(map[uint64]hook.CheckResult)
+	for _, r := range e.To {
+		key := e.recipientKey(group, r)
+		pending := emailRecipients.pending[key]
+		for _, pc := range added {
+			if !containsChange(pending, pc.change) {
+				pending = append(pending, pc)
+			}
+		}
+		if len(pending) > maxPendingEmailChanges {
+			pending = pending[len(pending)-maxPendingEmailChanges:]
+		}
+		emailRecipients.pending[key] = pending
+
+		if last, ok := emailRecipients.sent[key]; ok && now.Sub(last) < limit {
+			continue
+		}
+		if sending, ok := emailRecipients.sending[key]; ok && now.Sub(sending.since) < limit {
+			continue
+		}
+		to = append(to, r)
+		for _, pc := range pending {
+			included[pc.seq] = pc.change
+		}
+	}
+
+	seqs := make([]uint64, 0, len(included))
+	for seq := range included {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	out := make([]hook.CheckResult, 0, len(seqs))
+	for _, seq := range seqs {
+		out = append(out, included[seq])
+	}
+	if len(seqs) == 0 {
+		return to, out, 0
+	}
+
+	seq := seqs[len(seqs)-1]
+	for _, r := range to {
+		emailRecipients.sending[e.recipientKey(group, r)] = emailSending{seq: seq, since: now}
+	}
+	return to, out, seq
+}
+
+// delivered records the recipients as emailed with the pending changes up to the sequence
+func (e Email) delivered(group string, to []string, seq uint64, now time.Time) {
+	emailRecipients.Lock()
+	defer emailRecipients.Unlock()
+
+	for _, r := range to {
+		key := e.recipientKey(group, r)
+		emailRecipients.sent[key] = now
+		if emailRecipients.sending[key].seq == seq {
+			delete(emailRecipients.sending, key)
+		}
+
+		pending := emailRecipients.pending[key]
+		i := 0
+		for i < len(pending) && pending[i].seq <= seq {
+			i++
+		}
+		if i == len(pending) {
+			delete(emailRecipients.pending, key)
+			continue
+		}
+		emailRecipients.pending[key] = pending[i:]
+	}
+}
+
+// abandoned releases the recipients of an email that will not be delivered, so the next email carries its changes
+func (e Email) abandoned(group string, to []string, seq uint64) {
+	emailRecipients.Lock()
+	defer emailRecipients.Unlock()
+
+	for _, r := range to {
+		key := e.recipientKey(group, r)
+		if emailRecipients.sending[key].seq == seq {
+			delete(emailRecipients.sending, key)
+		}
+	}
+}
+
+func (e Email) recipientKey(group, r string) string {
+	return group + "/" + e.Name + "/" + strings.ToLower(r)
+}
+
+// containsChange returns whether the change is pending already, like the change of a per-check event in the changes of its run
+func containsChange(pending []pendingEmailChange, cr hook.CheckResult) bool {
+	for _, pc := range pending {
+		if pc.change.Name == cr.Name && pc.change.Event == cr.Event && pc.change.Status == cr.Status && pc.change.FailingSince.Equal(cr.FailingSince) {
+			return true
+		}
+	}
+	return false
+}
+
+// compose renders the email with a plaintext and an HTML part
+func (e Email) compose(to []string, d emailDigest) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+
+	header := []string{
+		"From: " + e.From,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", d.Title),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + mw.Boundary(),
+	}
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	parts := []struct {
+		contentType string
+		execute     func(w *quotedprintable.Writer) error
+	}{
+		{"text/plain; charset=utf-8", func(w *quotedprintable.Writer) error { return emailText.Execute(w, d) }},
+		{"text/html; charset=utf-8", func(w *quotedprintable.Writer) error { return emailHTML.Execute(w, d) }},
+	}
+
+	for _, part := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if err := part.execute(qw); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// emailMessage defines an email prepared for delivery
+type emailMessage struct {
+	email    Email
+	group    string
+	password string
+	to       []string
+	seq      uint64
+	data     []byte
+}
+
+// Send delivers the email and records the recipients as emailed once the server accepted it.
+// The recipients stay reserved when an attempt fails, so that other emails do not overtake its retries.
+func (m *emailMessage) Send(ctx context.Context, timeout time.Duration) (int, error) {
+	if err := m.send(ctx, timeout); err != nil {
+		return 0, err
+	}
+	m.email.delivered(m.group, m.to, m.seq, time.Now())
+	return 0, nil
+}
+
+// Abandon releases the recipients once the email is given up
+func (m *emailMessage) Abandon() {
+	m.email.abandoned(m.group, m.to, m.seq)
+}
+
+// send delivers the email with a single SMTP session
+func (m *emailMessage) send(ctx context.Context, timeout time.Duration) error {
+	e := m.email
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(m.port()))
+	dialer := &net.Dialer{Timeout: timeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if e.TLS == EmailTLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: e.Host})
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	c, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.TLS == "" || e.TLS == EmailStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: e.Host}); err != nil {
+			return err
+		}
+	}
+
+	if e.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.Username, m.password, e.Host)); err != nil {
+			return err
+		}
+	}
+
+	from, _ := mail.ParseAddress(e.From)
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range m.to {
+		rcpt, err := mail.ParseAddress(to)
+		if err != nil {
+			return err
+		}
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(m.data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Destination returns the address of the SMTP server
+func (m *emailMessage) Destination() string {
+	return "smtp://" + net.JoinHostPort(m.email.Host, strconv.Itoa(m.port()))
+}
+
+// Content returns the email
+func (m *emailMessage) Content() []byte {
+	return m.data
+}
+
+func (m *emailMessage) port() int {
+	if m.email.Port == 0 {
+		if m.email.TLS == EmailTLS {
+			return 465
+		}
+		return defaultSMTPPort
+	}
+	return m.email.Port
+}
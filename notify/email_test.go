@@ -0,0 +1,270 @@
+package notify
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+// smtpMessage defines an email accepted by the SMTP stand-in
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is a local SMTP stand-in recording the emails it accepts. It rejects them while reject is set.
+type smtpServer struct {
+	listener net.Listener
+	messages chan smtpMessage
+	reject   int32
+}
+
+// newSMTPServer starts an SMTP stand-in without TLS. The server must be closed by the caller.
+func newSMTPServer(t *testing.T) *smtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	s := &smtpServer{listener: l, messages: make(chan smtpMessage, 10)}
+	go s.serve()
+	return s
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(textproto.NewConn(conn))
+	}
+}
+
+func (s *smtpServer) handle(c *textproto.Conn) {
+	defer c.Close()
+
+	c.PrintfLine("220 localhost ESMTP")
+	msg := smtpMessage{}
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			c.PrintfLine("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			if atomic.LoadInt32(&s.reject) == 1 {
+				c.PrintfLine("451 try again later")
+				continue
+			}
+			msg = smtpMessage{from: strings.Trim(line[len("MAIL FROM:"):], "<>")}
+			c.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			c.PrintfLine("250 OK")
+		case cmd == "DATA":
+			c.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			s.messages <- msg
+			c.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) email(name string, to ...string) Email {
+	return Email{
+		Name:   name,
+		Host:   "127.0.0.1",
+		Port:   s.listener.Addr().(*net.TCPAddr).Port,
+		TLS:    EmailNoTLS,
+		From:   "Kubecheck <kubecheck@example.com>",
+		To:     to,
+		Events: []hook.Event{"OnCheckFailed", "OnCheckRecovered"},
+	}
+}
+
+func (s *smtpServer) Close() {
+	s.listener.Close()
+}
+
+// received returns the next email accepted by the stand-in
+func (s *smtpServer) received(t *testing.T) smtpMessage {
+	t.Helper()
+
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no email received")
+	}
+	return smtpMessage{}
+}
+
+// sendEmail prepares and sends the email for the payload
+func sendEmail(t *testing.T, e Email, p hook.Payload) {
+	t.Helper()
+
+	msg, err := e.Prepare(p)
+	if err != nil || msg == nil {
+		t.Fatalf("Prepare() = %v, %v", msg, err)
+	}
+	if _, err := msg.Send(context.Background(), time.Second); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+}
+
+// changedCheck returns the payload of a per-check event of a failing check with the given name
+func changedCheck(name string) hook.Payload {
+	c := failingCheck()
+	c.Name = name
+	return checkPayload(c)
+}
+
+func TestEmailDigest(t *testing.T) {
+	s := newSMTPServer(t)
+	defer s.Close()
+
+	e := s.email("digest", "Platform <platform@example.com>", "oncall@example.com")
+	sendEmail(t, e, checkPayload(failingCheck()))
+
+	msg := s.received(t)
+	if msg.from != "kubecheck@example.com" || strings.Join(msg.to, ",") != "platform@example.com,oncall@example.com" {
+		t.Errorf("envelope = %s to %v", msg.from, msg.to)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(msg.data))
+	if err != nil {
+		t.Fatalf("invalid email: %v", err)
+	}
+	if got, want := m.Header.Get("Subject"), changesTitle("kubecheck", []hook.CheckResult{failingCheck()}); got != want {
+		t.Errorf("Subject = %s, want %s", got, want)
+	}
+	if got := m.Header.Get("Content-Type"); !strings.HasPrefix(got, "multipart/alternative") {
+		t.Errorf("Content-Type = %s", got)
+	}
+	for _, want := range []string{"text/plain", "text/html", "dns", "lookup failed", "Run run-1"} {
+		if !strings.Contains(msg.data, want) {
+			t.Errorf("email does not contain %q", want)
+		}
+	}
+}
+
+func TestEmailRateLimit(t *testing.T) {
+	s := newSMTPServer(t)
+	defer s.Close()
+
+	e := s.email("rate-limit", "platform@example.com")
+	sendEmail(t, e, changedCheck("a"))
+	s.received(t)
+
+	if msg, err := e.Prepare(changedCheck("b")); err != nil || msg != nil {
+		t.Fatalf("Prepare() within the rate limit = %v, %v, want nil", msg, err)
+	}
+
+	to, changes, _ := e.pending("", time.Now().Add(defaultEmailRateLimit), nil)
+	if len(to) != 1 || len(changes) != 1 || changes[0].Name != "b" {
+		t.Errorf("pending() after the rate limit = %v, %+v, want the held back change of b", to, changes)
+	}
+}
+
+func TestEmailRetryKeepsRecipientsReserved(t *testing.T) {
+	s := newSMTPServer(t)
+	defer s.Close()
+	atomic.StoreInt32(&s.reject, 1)
+
+	e := s.email("retry", "platform@example.com")
+	msg, err := e.Prepare(changedCheck("a"))
+	if err != nil || msg == nil {
+		t.Fatalf("Prepare() = %v, %v", msg, err)
+	}
+	if _, err := msg.Send(context.Background(), time.Second); err == nil {
+		t.Fatal("Send() succeeded, want the rejection")
+	}
+
+	// The failed attempt is retried, so other emails do not overtake it
+	if next, err := e.Prepare(changedCheck("b")); err != nil || next != nil {
+		t.Fatalf("Prepare() while retrying = %v, %v, want nil", next, err)
+	}
+
+	msg.(hook.Abandoner).Abandon()
+	atomic.StoreInt32(&s.reject, 0)
+
+	sendEmail(t, e, changedCheck("c"))
+	data := s.received(t).data
+	for _, name := range []string{"a", "b", "c"} {
+		if !strings.Contains(data, "- "+name+": ") {
+			t.Errorf("email does not contain the change of %s:\n%s", name, data)
+		}
+	}
+}
+
+func TestEmailAbandonedByDispatcher(t *testing.T) {
+	s := newSMTPServer(t)
+	defer s.Close()
+	atomic.StoreInt32(&s.reject, 1)
+
+	e := s.email("dead-letter", "platform@example.com")
+	d := hook.NewDispatcher(hook.DeliveryConfig{Timeout: time.Second, Retries: 1, Backoff: time.Millisecond})
+	d.Enqueue(context.Background(), []hook.Notifier{e}, changedCheck("a"))
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if recent := d.Recent(); len(recent) != 1 || recent[0].Status != hook.DeliveryFailed || len(recent[0].Attempts) != 2 {
+		t.Fatalf("deliveries = %+v, want one failed after 2 attempts", recent)
+	}
+
+	to, changes, _ := e.pending("", time.Now(), nil)
+	if len(to) != 1 || len(changes) != 1 || changes[0].Name != "a" {
+		t.Errorf("pending() after the delivery failed = %v, %+v, want the recipient released with the change of a", to, changes)
+	}
+}
+
+func TestEmailGroups(t *testing.T) {
+	s := newSMTPServer(t)
+	defer s.Close()
+
+	e := s.email("groups", "platform@example.com")
+	sendEmail(t, e, changedCheck("a"))
+	s.received(t)
+
+	// The same notifier of a group is rate limited separately
+	grouped := changedCheck("b")
+	grouped.Run.Group = "core"
+	sendEmail(t, e, grouped)
+	if data := s.received(t).data; !strings.Contains(data, "- b: ") || strings.Contains(data, "- a: ") {
+		t.Errorf("email of the group:\n%s\nwant only the change of b", data)
+	}
+
+	if msg, err := e.Prepare(changedCheck("c")); err != nil || msg != nil {
+		t.Errorf("Prepare() of the instance = %v, %v, want it rate limited", msg, err)
+	}
+}
+
+func TestEmailTags(t *testing.T) {
+	e := Email{Name: "tags", Tags: map[string]string{"kind": "network"}}
+	matched := e.matching([]hook.CheckResult{failingCheck(), {Name: "api", Status: checks.Failed}})
+	if len(matched) != 1 || matched[0].Name != "dns" {
+		t.Errorf("matching() = %+v, want dns", matched)
+	}
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+// Heartbeat pings a dead man's switch like healthchecks.io or Cronitor with the outcome of each run.
+// It pings StartURL when a run starts, and SuccessURL or FailURL with a summary of the run when it has completed.
+// A dead man's switch then alerts both when kubecheck stops running and when checks fail.
+type Heartbeat struct {
+	Name string
+	// URL sets the URLs using the convention of healthchecks.io: URL/start, URL and URL/fail.
+	// It may reference environment variables.
+	URL string
+	// StartURL, SuccessURL and FailURL override the URLs derived from URL. A run is not pinged if its URL is empty.
+	StartURL   string
+	SuccessURL string
+	FailURL    string
+	// Method is the HTTP method. Defaults to POST.
+	Method string
+}
+
+// Describe returns the description of the notifier
+func (h Heartbeat) Describe() hook.NotifierDescription {
+	return hook.NotifierDescription{Name: h.Name, Type: "heartbeat"}
+}
+
+// Subscribes returns whether the heartbeat pings for the event, which is the start of a run if there is a start URL, and its completion
+func (h Heartbeat) Subscribes(e hook.Event) bool {
+	switch e {
+	case startedEvent:
+		return h.urls()[0] != ""
+	case completedEvent:
+		return true
+	}
+	return false
+}
+
+// Validate checks that the heartbeat has a name, a success and a fail URL, and a supported method
+func (h Heartbeat) Validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("heartbeat notifier has no name")
+	}
+	urls := h.urls()
+	if urls[1] == "" || urls[2] == "" {
+		return fmt.Errorf("heartbeat notifier \"%s\" requires a url, or a success and a fail url", h.Name)
+	}
+	for _, u := range urls {
+		if _, err := hook.ExpandEnv(u); err != nil {
+			return fmt.Errorf("heartbeat notifier \"%s\": %v", h.Name, err)
+		}
+	}
+	switch strings.ToUpper(h.Method) {
+	case "", "POST", "PUT", "GET", "HEAD":
+	default:
+		return fmt.Errorf("heartbeat notifier \"%s\" has an unsupported method \"%s\"", h.Name, h.Method)
+	}
+	return nil
+}
+
+// Prepare creates the ping for the payload, with a summary of the run in the body unless the method is GET or HEAD.
+// Partial runs are not pinged, since a single passing check does not mean that all checks pass.
+func (h Heartbeat) Prepare(p hook.Payload) (hook.Message, error) {
+	if p.Run.Partial {
+		return nil, nil
+	}
+
+	urls := h.urls()
+
+	var url string
+	switch {
+	case p.Event == startedEvent:
+		url = urls[0]
+	case p.Event == completedEvent && p.Run.Status == checks.Passed:
+		url = urls[1]
+	case p.Event == completedEvent && p.Run.Status == checks.Failed:
+		url = urls[2]
+	}
+	if url == "" {
+		return nil, nil
+	}
+
+	url, err := hook.ExpandEnv(url)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &hook.Request{
+		Method:      strings.ToUpper(h.Method),
+		URL:         url,
+		ContentType: "text/plain; charset=utf-8",
+	}
+	if req.Method != "GET" && req.Method != "HEAD" {
+		req.Body = []byte(summary(p))
+	}
+	return req, nil
+}
+
+// urls returns the start, success and fail URLs
+func (h Heartbeat) urls() [3]string {
+	urls := [3]string{h.StartURL, h.SuccessURL, h.FailURL}
+	if base := strings.TrimSuffix(h.URL, "/"); base != "" {
+		defaults := [3]string{base + "/start", base, base + "/fail"}
+		for i := range urls {
+			if urls[i] == "" {
+				urls[i] = defaults[i]
+			}
+		}
+	}
+	return urls
+}
+
+// summary describes a run and its failed checks in plain text
+func summary(p hook.Payload) string {
+	if p.Run.Status != checks.Passed && p.Run.Status != checks.Failed {
+		return fmt.Sprintf("Run %s started with %d checks\n", p.Run.ID, p.Run.Total)
+	}
+
+	lines := []string{
+		fmt.Sprintf("Run %s %s in %s: %d passed, %d failed, %d disabled of %d checks", p.Run.ID, p.Run.Status, p.Run.Duration, p.Run.Passed, p.Run.Failed, p.Run.Disabled, p.Run.Total),
+	}
+	for _, c := range p.Failed {
+		lines = append(lines, fmt.Sprintf("%s: %s", c.Name, c.Reason))
+		for _, a := range c.Assertions {
+			lines = append(lines, "  "+assertion(a))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+func TestHeartbeatPings(t *testing.T) {
+	server, requests := newReceiver(t)
+	defer server.Close()
+
+	h := Heartbeat{Name: "heartbeat", URL: server.URL + "/ping/uuid", Method: "POST"}
+
+	started := hook.Payload{Event: startedEvent, Run: hook.RunSummary{ID: "run-1", Status: "running", Total: 2}}
+	if r := send(t, h, started, requests); r.path != "/ping/uuid/start" {
+		t.Errorf("started pinged %s, want /ping/uuid/start", r.path)
+	}
+
+	r := send(t, h, completedPayload(), requests)
+	if r.path != "/ping/uuid/fail" {
+		t.Errorf("failed run pinged %s, want /ping/uuid/fail", r.path)
+	}
+	if body := string(r.body); !strings.HasPrefix(body, "Run run-1 failed in 2s: 1 passed, 1 failed, 0 disabled of 2 checks\ndns: lookup failed\n") {
+		t.Errorf("body = %q", body)
+	}
+
+	passed := hook.Payload{Event: completedEvent, Run: hook.RunSummary{ID: "run-2", Status: "running", Total: 0}}
+	passed.Complete(testStart)
+	if r := send(t, h, passed, requests); r.path != "/ping/uuid" {
+		t.Errorf("passed run pinged %s, want /ping/uuid", r.path)
+	}
+}
+
+func TestHeartbeatSkipsPartialRuns(t *testing.T) {
+	h := Heartbeat{Name: "heartbeat", URL: "http://hc-ping.com/uuid"}
+	p := completedPayload()
+	p.Run.Partial = true
+	skipped(t, h, p)
+}
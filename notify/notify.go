@@ -0,0 +1,196 @@
+// Package notify provides notifiers for chat and incident management services, heartbeats and metrics, delivered like webhooks
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+const (
+	defaultSource = "kubecheck"
+	// maxChecks limits the number of failed checks listed in a message
+	maxChecks = 10
+	// maxAssertions limits the number of failed assertions listed per check
+	maxAssertions = 5
+	// The events of the config package, which this package cannot import
+	startedEvent      hook.Event = "OnHealthcheckStarted"
+	completedEvent    hook.Event = "OnHealthcheckCompleted"
+	stillFailingEvent hook.Event = "OnCheckStillFailing"
+	groupedEvent      hook.Event = "OnChecksChanged"
+)
+
+// subscribes returns whether the events contain the event
+func subscribes(events []hook.Event, e hook.Event) bool {
+	for _, a := range events {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the fields shared by all notifiers
+func validate(kind string, name string, url string, events []hook.Event) error {
+	if name == "" {
+		return fmt.Errorf("%s notifier has no name", kind)
+	}
+	if url == "" {
+		return fmt.Errorf("%s notifier \"%s\" has no url", kind, name)
+	}
+	if _, err := hook.ExpandEnv(url); err != nil {
+		return fmt.Errorf("%s notifier \"%s\": url: %v", kind, name, err)
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("%s notifier \"%s\" has no events", kind, name)
+	}
+	return nil
+}
+
+func sourceOrDefault(source string) string {
+	if source == "" {
+		return defaultSource
+	}
+	return source
+}
+
+// title returns a one line summary of the payload
+func title(source string, p hook.Payload) string {
+	if p.Event == groupedEvent {
+		return changesTitle(source, p.Changes)
+	}
+
+	if c := p.Check; c != nil {
+		switch c.Status {
+		case checks.Failed:
+			if p.Event == stillFailingEvent {
+				return fmt.Sprintf("[%s] %s is still failing since %s", source, c.Name, c.FailingSince.Format(time.RFC3339))
+			}
+			return fmt.Sprintf("[%s] %s is failing", source, c.Name)
+		case checks.Passed:
+			if !c.FailingSince.IsZero() {
+				return fmt.Sprintf("[%s] %s recovered after %s", source, c.Name, p.Run.StartedAt.Sub(c.FailingSince).Round(time.Second))
+			}
+			return fmt.Sprintf("[%s] %s recovered", source, c.Name)
+		}
+		return fmt.Sprintf("[%s] %s is %s", source, c.Name, c.Status)
+	}
+
+	switch p.Run.Status {
+	case checks.Failed:
+		return fmt.Sprintf("[%s] %d of %d checks failed", source, p.Run.Failed, p.Run.Total)
+	case checks.Passed:
+		return fmt.Sprintf("[%s] all %d checks passed", source, p.Run.Passed)
+	}
+	return fmt.Sprintf("[%s] run %s started with %d checks", source, p.Run.ID, p.Run.Total)
+}
+
+// changesTitle returns a one line summary of status changes
+func changesTitle(source string, changes []hook.CheckResult) string {
+	if len(changes) == 1 {
+		c := changes[0]
+		return fmt.Sprintf("[%s] %s %s", source, c.Name, change(c))
+	}
+
+	failing, recovered := 0, 0
+	for _, c := range changes {
+		if c.Status == checks.Failed {
+			failing++
+		} else {
+			recovered++
+		}
+	}
+	return fmt.Sprintf("[%s] %d checks changed: %d failing, %d recovered", source, len(changes), failing, recovered)
+}
+
+// reported returns the checks listed in messages about the payload: the changed check of per-check events if it failed,
+// all changes of grouped events, and the failed checks of run events
+func reported(p hook.Payload) []hook.CheckResult {
+	if p.Event == groupedEvent {
+		return p.Changes
+	}
+	if p.Check != nil {
+		if p.Check.Status == checks.Failed {
+			return []hook.CheckResult{*p.Check}
+		}
+		return nil
+	}
+	return p.Failed
+}
+
+// failed returns whether the payload reports a failure, as opposed to a recovery or a passed run
+func failed(p hook.Payload) bool {
+	if p.Event == groupedEvent {
+		return len(p.Failed) > 0
+	}
+	if p.Check != nil {
+		return p.Check.Status == checks.Failed
+	}
+	return p.Run.Status == checks.Failed
+}
+
+// change describes the status change of a check
+func change(c hook.CheckResult) string {
+	switch {
+	case c.Event == stillFailingEvent:
+		return "is still failing"
+	case c.Status == checks.Failed:
+		return "started failing"
+	case c.Status == checks.Passed:
+		return "recovered"
+	}
+	return c.Status
+}
+
+// owner returns a one line description of the owner of a check, or an empty string if it has none
+func owner(o checks.Owner) string {
+	parts := make([]string, 0, 3)
+	for _, p := range []string{o.Team, o.Contact, o.RunbookURL} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Owner: " + strings.Join(parts, ", ")
+}
+
+// assertion returns a one line description of a failed assertion
+func assertion(a checks.FailedAssertion) string {
+	s := a.Type
+	if a.Group != "" {
+		s = a.Group + " " + s
+	}
+	return fmt.Sprintf("%s: expected %s, got %s", s, a.Expected, a.Actual)
+}
+
+// truncate shortens a string to at most n characters
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// more returns a line noting the number of omitted items, if any
+func more(total int, shown int, what string) string {
+	if total <= shown {
+		return ""
+	}
+	return fmt.Sprintf("and %d more %s", total-shown, what)
+}
+
+func joinLines(lines ...string) string {
+	out := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if l != "" {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
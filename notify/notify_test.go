@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+// received defines a request received by a test server
+type received struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts a test server recording the requests it receives. The server must be closed by the caller.
+func newReceiver(t *testing.T) (*httptest.Server, <-chan received) {
+	requests := make(chan received, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading body: %v", err)
+		}
+		requests <- received{method: r.Method, path: r.URL.Path, header: r.Header, body: body}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	return server, requests
+}
+
+// send prepares and sends the message of the notifier for the payload, and returns the request received by the server
+func send(t *testing.T, n hook.Notifier, p hook.Payload, requests <-chan received) received {
+	t.Helper()
+
+	msg, err := n.Prepare(p)
+	if err != nil {
+		t.Fatalf("Prepare() error: %v", err)
+	}
+	if msg == nil {
+		t.Fatal("Prepare() skipped the payload")
+	}
+	if _, err := msg.Send(context.Background(), time.Second); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	select {
+	case r := <-requests:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("no request received")
+	}
+	return received{}
+}
+
+// skipped asserts that the notifier does not send a message for the payload
+func skipped(t *testing.T, n hook.Notifier, p hook.Payload) {
+	t.Helper()
+
+	msg, err := n.Prepare(p)
+	if err != nil {
+		t.Fatalf("Prepare() error: %v", err)
+	}
+	if msg != nil {
+		t.Errorf("Prepare() = %s, want the payload to be skipped", msg.Content())
+	}
+}
+
+func decode(t *testing.T, body []byte, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", body, err)
+	}
+}
+
+var (
+	testStart        = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	testFailingSince = testStart.Add(-10 * time.Minute)
+)
+
+// failingCheck returns a check that started failing
+func failingCheck() hook.CheckResult {
+	return hook.CheckResult{
+		Name:     "dns",
+		Tags:     map[string]string{"kind": "network"},
+		Severity: "critical",
+		Owner:    checks.Owner{Team: "platform", RunbookURL: "https://runbooks.example.com/dns"},
+		Status:   checks.Failed,
+		Reason:   "lookup failed",
+		Assertions: []checks.FailedAssertion{
+			{Group: "records", Type: "Equals", Expected: "1", Actual: "0"},
+		},
+		Duration:     1500 * time.Millisecond,
+		FailingSince: testFailingSince,
+		Event:        "OnCheckFailed",
+	}
+}
+
+// recoveredCheck returns the failing check after it recovered
+func recoveredCheck() hook.CheckResult {
+	c := failingCheck()
+	c.Status = checks.Passed
+	c.Reason = ""
+	c.Assertions = nil
+	c.Event = "OnCheckRecovered"
+	return c
+}
+
+// checkPayload returns the payload of a per-check event in a running run
+func checkPayload(c hook.CheckResult) hook.Payload {
+	p := hook.Payload{
+		Event: c.Event,
+		Run:   hook.RunSummary{ID: "run-1", Status: "running", Total: 2, StartedAt: testStart},
+	}
+	p.Add(c)
+	p.Check = &c
+	p.Changes = []hook.CheckResult{c}
+	return p
+}
+
+// completedPayload returns the payload of a completed run of a passing and a failing check
+func completedPayload() hook.Payload {
+	p := hook.Payload{
+		Event: completedEvent,
+		Run:   hook.RunSummary{ID: "run-1", Status: "running", Total: 2, StartedAt: testStart},
+	}
+	p.Add(hook.CheckResult{Name: "api", Status: checks.Passed, Duration: 250 * time.Millisecond})
+	p.Add(failingCheck())
+	p.Changes = []hook.CheckResult{failingCheck()}
+	p.Complete(testStart.Add(2 * time.Second))
+	return p
+}
@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+// PagerDutyEventsURL is the endpoint of the PagerDuty Events API v2
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers and resolves PagerDuty incidents with the Events API v2.
+// Failed checks trigger an alert and recovered checks resolve it, using a dedup key that is stable per check.
+// Run events trigger and resolve a single alert for the run instead. Grouped changes are not supported.
+type PagerDuty struct {
+	Name string
+	// RoutingKey is the integration key of the service. It may reference environment variables like ${PAGERDUTY_ROUTING_KEY}.
+	RoutingKey string
+	Events     []hook.Event
+	// Source identifies this instance in alerts and dedup keys, e.g. the cluster name. Defaults to kubecheck.
+	Source string
+	// Severity of triggered alerts: critical, error, warning or info. Defaults to error.
+	Severity string
+	// URL of the Events API. Defaults to PagerDutyEventsURL.
+	URL string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Client      string            `json:"client,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// PagerDuty limits summaries to 1024 characters
+const maxPagerDutySummary = 1024
+
+// Describe returns the description of the notifier
+func (pd PagerDuty) Describe() hook.NotifierDescription {
+	return hook.NotifierDescription{Name: pd.Name, Type: "pagerduty"}
+}
+
+// Subscribes returns whether the notifier handles the event
+func (pd PagerDuty) Subscribes(e hook.Event) bool {
+	return subscribes(pd.Events, e)
+}
+
+// Validate checks that the notifier has a name, routing key, events and a valid severity
+func (pd PagerDuty) Validate() error {
+	if err := validate("pagerduty", pd.Name, pd.url(), pd.Events); err != nil {
+		return err
+	}
+	if pd.RoutingKey == "" {
+		return fmt.Errorf("pagerduty notifier \"%s\" has no routing key", pd.Name)
+	}
+	if _, err := hook.ExpandEnv(pd.RoutingKey); err != nil {
+		return fmt.Errorf("pagerduty notifier \"%s\": routing key: %v", pd.Name, err)
+	}
+	switch pd.Severity {
+	case "", "critical", "error", "warning", "info":
+	default:
+		return fmt.Errorf("pagerduty notifier \"%s\" has an unknown severity \"%s\"", pd.Name, pd.Severity)
+	}
+	return nil
+}
+
+// DedupKey returns the key identifying the alert of a check, or of the run if name is empty
+func (pd PagerDuty) DedupKey(name string) string {
+	if name == "" {
+		return fmt.Sprintf("kubecheck/%s/run", sourceOrDefault(pd.Source))
+	}
+	return fmt.Sprintf("kubecheck/%s/check/%s", sourceOrDefault(pd.Source), name)
+}
+
+// Prepare creates the trigger or resolve event for the payload. Grouped changes are skipped, since PagerDuty groups the alerts of checks itself.
+// Run events are skipped for runs that have not completed, and for partial runs, which would resolve the alert of the run
+// while the checks they left out still fail.
+func (pd PagerDuty) Prepare(p hook.Payload) (hook.Message, error) {
+	if p.Event == groupedEvent {
+		return nil, nil
+	}
+	if p.Check == nil && (p.Run.Partial || p.Run.Status != checks.Passed && p.Run.Status != checks.Failed) {
+		return nil, nil
+	}
+
+	url, err := hook.ExpandEnv(pd.url())
+	if err != nil {
+		return nil, err
+	}
+	key, err := hook.ExpandEnv(pd.RoutingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	name := ""
+	if p.Check != nil {
+		name = p.Check.Name
+	}
+
+	event := pagerDutyEvent{
+		RoutingKey:  key,
+		EventAction: "resolve",
+		DedupKey:    pd.DedupKey(name),
+		Client:      "kubecheck",
+	}
+
+	if failed(p) {
+		event.EventAction = "trigger"
+		event.Payload = pd.payload(p, name)
+		if p.Check != nil && p.Check.Owner.RunbookURL != "" {
+			event.Links = []pagerDutyLink{{Href: p.Check.Owner.RunbookURL, Text: "Runbook"}}
+		}
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hook.Request{
+		Method:      "POST",
+		URL:         url,
+		ContentType: "application/json",
+		Body:        data,
+	}, nil
+}
+
+func (pd PagerDuty) payload(p hook.Payload, name string) *pagerDutyPayload {
+	severity := pd.Severity
+	if severity == "" {
+		severity = "error"
+	}
+
+	details := map[string]interface{}{
+		"run": p.Run.ID,
+	}
+	timestamp := p.Run.StartedAt
+	if c := p.Check; c != nil {
+		details["reason"] = c.Reason
+		details["assertions"] = c.Assertions
+		if !c.Owner.IsZero() {
+			details["owner"] = c.Owner
+		}
+		if !c.FailingSince.IsZero() {
+			details["failingSince"] = c.FailingSince
+			timestamp = c.FailingSince
+		}
+	} else {
+		failed := make(map[string]string)
+		for _, c := range p.Failed {
+			failed[c.Name] = c.Reason
+		}
+		details["failed"] = failed
+	}
+
+	payload := &pagerDutyPayload{
+		Summary:       truncate(maxPagerDutySummary, title(sourceOrDefault(pd.Source), p)),
+		Source:        sourceOrDefault(pd.Source),
+		Severity:      severity,
+		Component:     name,
+		Group:         group(p),
+		CustomDetails: details,
+	}
+	if !timestamp.IsZero() {
+		payload.Timestamp = timestamp.Format(time.RFC3339)
+	}
+	return payload
+}
+
+// group returns the team owning the check of the payload
+func group(p hook.Payload) string {
+	if p.Check == nil {
+		return ""
+	}
+	return p.Check.Owner.Team
+}
+
+func (pd PagerDuty) url() string {
+	if pd.URL == "" {
+		return PagerDutyEventsURL
+	}
+	return pd.URL
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+func TestPagerDutyTriggerAndResolve(t *testing.T) {
+	server, requests := newReceiver(t)
+	defer server.Close()
+
+	pd := PagerDuty{
+		Name:       "pagerduty",
+		RoutingKey: "routing-key",
+		Events:     []hook.Event{"OnCheckFailed", "OnCheckRecovered"},
+		Source:     "prod",
+		URL:        server.URL + "/v2/enqueue",
+	}
+
+	r := send(t, pd, checkPayload(failingCheck()), requests)
+	if r.path != "/v2/enqueue" {
+		t.Errorf("path = %s, want /v2/enqueue", r.path)
+	}
+
+	var trigger pagerDutyEvent
+	decode(t, r.body, &trigger)
+	if trigger.RoutingKey != "routing-key" || trigger.EventAction != "trigger" || trigger.DedupKey != "kubecheck/prod/check/dns" {
+		t.Errorf("event = %s, want a trigger of kubecheck/prod/check/dns", r.body)
+	}
+	if trigger.Payload == nil {
+		t.Fatal("trigger has no payload")
+	}
+	p := trigger.Payload
+	if p.Summary != "[prod] dns is failing" || p.Source != "prod" || p.Severity != "error" || p.Component != "dns" || p.Group != "platform" {
+		t.Errorf("payload = %+v", p)
+	}
+	if p.Timestamp != "2020-06-01T11:50:00Z" {
+		t.Errorf("timestamp = %s, want when the check started failing", p.Timestamp)
+	}
+	if p.CustomDetails["reason"] != "lookup failed" || p.CustomDetails["run"] != "run-1" {
+		t.Errorf("custom details = %v", p.CustomDetails)
+	}
+	if len(trigger.Links) != 1 || trigger.Links[0].Href != "https://runbooks.example.com/dns" {
+		t.Errorf("links = %+v, want the runbook", trigger.Links)
+	}
+
+	r = send(t, pd, checkPayload(recoveredCheck()), requests)
+
+	var resolve pagerDutyEvent
+	decode(t, r.body, &resolve)
+	if resolve.EventAction != "resolve" || resolve.DedupKey != trigger.DedupKey || resolve.Payload != nil {
+		t.Errorf("event = %s, want a resolve of %s", r.body, trigger.DedupKey)
+	}
+}
+
+func TestPagerDutyRun(t *testing.T) {
+	server, requests := newReceiver(t)
+	defer server.Close()
+
+	pd := PagerDuty{Name: "pagerduty", RoutingKey: "routing-key", Events: []hook.Event{completedEvent}, Severity: "critical", URL: server.URL}
+	r := send(t, pd, completedPayload(), requests)
+
+	var event pagerDutyEvent
+	decode(t, r.body, &event)
+	if event.EventAction != "trigger" || event.DedupKey != "kubecheck/kubecheck/run" {
+		t.Errorf("event = %s, want a trigger of the run", r.body)
+	}
+	if event.Payload == nil || event.Payload.Severity != "critical" || event.Payload.Summary != "[kubecheck] 1 of 2 checks failed" {
+		t.Fatalf("payload = %+v", event.Payload)
+	}
+	failed, ok := event.Payload.CustomDetails["failed"].(map[string]interface{})
+	if !ok || failed["dns"] != "lookup failed" {
+		t.Errorf("failed = %v, want dns", event.Payload.CustomDetails["failed"])
+	}
+}
+
+func TestPagerDutySkipsGroupedChanges(t *testing.T) {
+	pd := PagerDuty{Name: "pagerduty", RoutingKey: "routing-key", Events: []hook.Event{groupedEvent}}
+	p := completedPayload()
+	p.Event = groupedEvent
+	skipped(t, pd, p)
+}
+
+func TestPagerDutySkipsPartialRuns(t *testing.T) {
+	pd := PagerDuty{Name: "pagerduty", RoutingKey: "routing-key", Events: []hook.Event{completedEvent, "OnCheckFailed"}}
+
+	passed := hook.Payload{Event: completedEvent, Run: hook.RunSummary{ID: "run-2", Status: "running", Partial: true}}
+	passed.Complete(testStart)
+	skipped(t, pd, passed)
+
+	failed := completedPayload()
+	failed.Run.Partial = true
+	skipped(t, pd, failed)
+
+	check := failingCheck()
+	p := hook.Payload{Event: "OnCheckFailed", Check: &check, Run: hook.RunSummary{ID: "run-2", Status: "running", Partial: true}}
+	if msg, err := pd.Prepare(p); err != nil || msg == nil {
+		t.Errorf("Prepare() of a check of a partial run = %v, %v, want a message", msg, err)
+	}
+}
@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+const defaultPushgatewayJob = "kubecheck"
+
+// Pushgateway pushes the status and duration of each check to a Prometheus Pushgateway when a run has completed.
+// It replaces the metrics of its grouping key on every push, so checks that are removed disappear, and metrics survive
+// kubecheck running as a CronJob in run-once mode where there is no process left to scrape.
+type Pushgateway struct {
+	Name string
+	// URL of the Pushgateway, e.g. http://pushgateway:9091. It may reference environment variables.
+	URL string
+	// Job is the job label of the grouping key. Defaults to kubecheck.
+	Job string
+	// Grouping adds labels to the grouping key, e.g. the cluster
+	Grouping map[string]string
+	// Headers are added to each request. Values may reference environment variables like ${PUSHGATEWAY_TOKEN}.
+	Headers map[string]string
+}
+
+// Describe returns the description of the notifier
+func (pg Pushgateway) Describe() hook.NotifierDescription {
+	return hook.NotifierDescription{Name: pg.Name, Type: "pushgateway"}
+}
+
+// Subscribes returns whether the event is the completion of a run, which is the only event pushed
+func (pg Pushgateway) Subscribes(e hook.Event) bool {
+	return e == completedEvent
+}
+
+// Validate checks that the notifier has a name, a url, valid grouping labels and that referenced environment variables are set
+func (pg Pushgateway) Validate() error {
+	if err := validate("pushgateway", pg.Name, pg.URL, []hook.Event{completedEvent}); err != nil {
+		return err
+	}
+	for k := range pg.Grouping {
+		if k == "" || k == "job" || labelName(k) != k {
+			return fmt.Errorf("pushgateway notifier \"%s\" has an invalid grouping label \"%s\"", pg.Name, k)
+		}
+	}
+	for k, v := range pg.Headers {
+		if _, err := hook.ExpandEnv(v); err != nil {
+			return fmt.Errorf("pushgateway notifier \"%s\": header %s: %v", pg.Name, k, err)
+		}
+	}
+	return nil
+}
+
+// Prepare creates the push of the metrics of a completed run. Partial runs are not pushed, since the push replaces the metrics of all checks.
+func (pg Pushgateway) Prepare(p hook.Payload) (hook.Message, error) {
+	if p.Run.Partial || (p.Run.Status != checks.Passed && p.Run.Status != checks.Failed) {
+		return nil, nil
+	}
+
+	base, err := hook.ExpandEnv(pg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string)
+	for k, v := range pg.Headers {
+		value, err := hook.ExpandEnv(v)
+		if err != nil {
+			return nil, err
+		}
+		headers[k] = value
+	}
+
+	return &hook.Request{
+		Method:      "PUT",
+		URL:         pg.pushURL(base),
+		ContentType: "text/plain; version=0.0.4; charset=utf-8",
+		Headers:     headers,
+		Body:        exposition(p),
+	}, nil
+}
+
+// pushURL returns the URL of the grouping key of the notifier
+func (pg Pushgateway) pushURL(base string) string {
+	job := pg.Job
+	if job == "" {
+		job = defaultPushgatewayJob
+	}
+
+	names := make([]string, 0, len(pg.Grouping))
+	for k := range pg.Grouping {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	path := []string{strings.TrimSuffix(base, "/"), "metrics", groupingLabel("job", job)}
+	for _, k := range names {
+		path = append(path, groupingLabel(k, pg.Grouping[k]))
+	}
+	return strings.Join(path, "/")
+}
+
+// groupingLabel encodes a label of the grouping key as a path segment, with base64 for values the path cannot hold
+func groupingLabel(name string, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return name + "@base64/" + base64.URLEncoding.EncodeToString([]byte(value))
+	}
+	return name + "/" + url.PathEscape(value)
+}
+
+// exposition renders the metrics of a run in the Prometheus text format
+func exposition(p hook.Payload) []byte {
+	buf := &bytes.Buffer{}
+
+	metric := func(name string, help string) {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	sample := func(name string, labels string, v float64) {
+		fmt.Fprintf(buf, "%s%s %s\n", name, labels, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+
+	executed := make([]hook.CheckResult, 0, len(p.Results))
+	for _, cr := range p.Results {
+		if cr.Status == checks.Passed || cr.Status == checks.Failed {
+			executed = append(executed, cr)
+		}
+	}
+
+	metric("kubecheck_check_success", "Whether the check passed (1) or failed (0) in the last run.")
+	for _, cr := range executed {
+		sample("kubecheck_check_success", checkLabels(cr), boolValue(cr.Status == checks.Passed))
+	}
+	metric("kubecheck_check_duration_seconds", "How long the check took to execute in the last run.")
+	for _, cr := range executed {
+		sample("kubecheck_check_duration_seconds", checkLabels(cr), cr.Duration.Seconds())
+	}
+
+	metric("kubecheck_run_success", "Whether all checks passed (1) or any failed (0) in the last run.")
+	sample("kubecheck_run_success", "", boolValue(p.Run.Status == checks.Passed))
+	metric("kubecheck_run_duration_seconds", "How long the last run took.")
+	sample("kubecheck_run_duration_seconds", "", p.Run.Duration.Seconds())
+	metric("kubecheck_run_checks", "The number of checks by status in the last run.")
+	sample("kubecheck_run_checks", `{status="passed"}`, float64(p.Run.Passed))
+	sample("kubecheck_run_checks", `{status="failed"}`, float64(p.Run.Failed))
+	sample("kubecheck_run_checks", `{status="disabled"}`, float64(p.Run.Disabled))
+	metric("kubecheck_run_last_completion_timestamp_seconds", "When the last run completed, in seconds since the Unix epoch.")
+	sample("kubecheck_run_last_completion_timestamp_seconds", "", float64(p.Run.FinishedAt.UnixNano())/1e9)
+
+	return buf.Bytes()
+}
+
+// checkLabels returns the labels of the metrics of a check
+func checkLabels(cr hook.CheckResult) string {
+	labels := []string{`check="` + labelValue(cr.Name) + `"`}
+	if cr.Severity != "" {
+		labels = append(labels, `severity="`+labelValue(cr.Severity)+`"`)
+	}
+	if cr.Owner.Team != "" {
+		labels = append(labels, `team="`+labelValue(cr.Owner.Team)+`"`)
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue escapes a label value of the Prometheus text format
+func labelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
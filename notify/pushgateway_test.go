@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+func TestPushgatewayPush(t *testing.T) {
+	server, requests := newReceiver(t)
+	defer server.Close()
+
+	pg := Pushgateway{
+		Name:     "pushgateway",
+		URL:      server.URL + "/",
+		Grouping: map[string]string{"cluster": "prod", "zone": "eu/west"},
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	}
+	r := send(t, pg, completedPayload(), requests)
+
+	if r.method != "PUT" {
+		t.Errorf("method = %s, want PUT", r.method)
+	}
+	if r.path != "/metrics/job/kubecheck/cluster/prod/zone@base64/ZXUvd2VzdA==" {
+		t.Errorf("path = %s", r.path)
+	}
+	if got := r.header.Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %s", got)
+	}
+	if got := r.header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	want := `# HELP kubecheck_check_success Whether the check passed (1) or failed (0) in the last run.
+# TYPE kubecheck_check_success gauge
+kubecheck_check_success{check="api"} 1
+kubecheck_check_success{check="dns",severity="critical",team="platform"} 0
+# HELP kubecheck_check_duration_seconds How long the check took to execute in the last run.
+# TYPE kubecheck_check_duration_seconds gauge
+kubecheck_check_duration_seconds{check="api"} 0.25
+kubecheck_check_duration_seconds{check="dns",severity="critical",team="platform"} 1.5
+# HELP kubecheck_run_success Whether all checks passed (1) or any failed (0) in the last run.
+# TYPE kubecheck_run_success gauge
+kubecheck_run_success 0
+# HELP kubecheck_run_duration_seconds How long the last run took.
+# TYPE kubecheck_run_duration_seconds gauge
+kubecheck_run_duration_seconds 2
+# HELP kubecheck_run_checks The number of checks by status in the last run.
+# TYPE kubecheck_run_checks gauge
+kubecheck_run_checks{status="passed"} 1
+kubecheck_run_checks{status="failed"} 1
+kubecheck_run_checks{status="disabled"} 0
+# HELP kubecheck_run_last_completion_timestamp_seconds When the last run completed, in seconds since the Unix epoch.
+# TYPE kubecheck_run_last_completion_timestamp_seconds gauge
+kubecheck_run_last_completion_timestamp_seconds 1.591012802e+09
+`
+	if got := string(r.body); got != want {
+		t.Errorf("exposition =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPushgatewayURL(t *testing.T) {
+	tests := []struct {
+		pg   Pushgateway
+		want string
+	}{
+		{Pushgateway{}, "http://pushgateway:9091/metrics/job/kubecheck"},
+		{Pushgateway{Job: "nightly"}, "http://pushgateway:9091/metrics/job/nightly"},
+		{Pushgateway{Grouping: map[string]string{"b": "2", "a": "1"}}, "http://pushgateway:9091/metrics/job/kubecheck/a/1/b/2"},
+		{Pushgateway{Grouping: map[string]string{"cluster": ""}}, "http://pushgateway:9091/metrics/job/kubecheck/cluster@base64/"},
+		{Pushgateway{Grouping: map[string]string{"name": "a b"}}, "http://pushgateway:9091/metrics/job/kubecheck/name/a%20b"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.pg.pushURL("http://pushgateway:9091/"); got != tt.want {
+			t.Errorf("pushURL() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	if got := labelValue("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("labelValue() = %s", got)
+	}
+}
+
+func TestPushgatewaySkipsPartialRuns(t *testing.T) {
+	pg := Pushgateway{Name: "pushgateway", URL: "http://pushgateway:9091"}
+
+	partial := completedPayload()
+	partial.Run.Partial = true
+	skipped(t, pg, partial)
+
+	started := hook.Payload{Event: startedEvent, Run: hook.RunSummary{ID: "run-1", Status: "running"}}
+	skipped(t, pg, started)
+}
@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+// Slack posts messages with blocks listing the failed checks and assertions to a Slack incoming webhook
+type Slack struct {
+	Name string
+	// URL of the incoming webhook. It may reference environment variables like ${SLACK_WEBHOOK_URL}.
+	URL    string
+	Events []hook.Event
+	// Source identifies this instance in messages, e.g. the cluster name. Defaults to kubecheck.
+	Source string
+	// Channel overrides the default channel of the incoming webhook
+	Channel string
+	// Username overrides the default username of the incoming webhook
+	Username string
+}
+
+type slackMessage struct {
+	Text     string       `json:"text"`
+	Blocks   []slackBlock `json:"blocks"`
+	Channel  string       `json:"channel,omitempty"`
+	Username string       `json:"username,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Slack limits section texts to 3000 characters
+const maxSlackText = 3000
+
+// Describe returns the description of the notifier
+func (s Slack) Describe() hook.NotifierDescription {
+	return hook.NotifierDescription{Name: s.Name, Type: "slack"}
+}
+
+// Subscribes returns whether the notifier handles the event
+func (s Slack) Subscribes(e hook.Event) bool {
+	return subscribes(s.Events, e)
+}
+
+// Validate checks that the notifier has a name, url and events
+func (s Slack) Validate() error {
+	return validate("slack", s.Name, s.URL, s.Events)
+}
+
+// Prepare creates the message for the payload
+func (s Slack) Prepare(p hook.Payload) (hook.Message, error) {
+	url, err := hook.ExpandEnv(s.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	icon := ":large_green_circle:"
+	if failed(p) {
+		icon = ":red_circle:"
+	}
+	text := title(sourceOrDefault(s.Source), p)
+
+	msg := slackMessage{
+		Text:     text,
+		Channel:  s.Channel,
+		Username: s.Username,
+		Blocks: []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: icon + " *" + slackEscape(text) + "*"}},
+		},
+	}
+
+	listed := reported(p)
+	for i, c := range listed {
+		if i == maxChecks {
+			msg.Blocks = append(msg.Blocks, slackContext(more(len(listed), maxChecks, "checks")))
+			break
+		}
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: truncate(maxSlackText, slackCheck(c, p.Event == groupedEvent))},
+		})
+	}
+
+	msg.Blocks = append(msg.Blocks, slackContext(fmt.Sprintf("Run %s, %d passed, %d failed, %d disabled", p.Run.ID, p.Run.Passed, p.Run.Failed, p.Run.Disabled)))
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hook.Request{
+		Method:      "POST",
+		URL:         url,
+		ContentType: "application/json",
+		Body:        body,
+	}, nil
+}
+
+// slackCheck describes a check, with its status change if showChange is set
+func slackCheck(c hook.CheckResult, showChange bool) string {
+	name := "*" + slackEscape(c.Name) + "*"
+	if showChange {
+		name += " " + change(c)
+	}
+	lines := []string{name}
+	if c.Reason != "" {
+		lines = append(lines, slackEscape(c.Reason))
+	}
+	if o := c.Owner; !o.IsZero() {
+		parts := make([]string, 0, 3)
+		for _, p := range []string{o.Team, o.Contact} {
+			if p != "" {
+				parts = append(parts, slackEscape(p))
+			}
+		}
+		if o.RunbookURL != "" {
+			parts = append(parts, "<"+o.RunbookURL+"|Runbook>")
+		}
+		lines = append(lines, "_Owner: "+strings.Join(parts, ", ")+"_")
+	}
+	for i, a := range c.Assertions {
+		if i == maxAssertions {
+			lines = append(lines, "• "+more(len(c.Assertions), maxAssertions, "failed assertions"))
+			break
+		}
+		lines = append(lines, "• "+slackEscape(assertion(a)))
+	}
+	return joinLines(lines...)
+}
+
+func slackContext(text string) slackBlock {
+	return slackBlock{
+		Type:     "context",
+		Elements: []slackText{{Type: "mrkdwn", Text: slackEscape(text)}},
+	}
+}
+
+// slackEscape escapes the control characters of Slack message formatting
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+func TestSlackCheckFailed(t *testing.T) {
+	server, requests := newReceiver(t)
+	defer server.Close()
+
+	s := Slack{Name: "slack", URL: server.URL + "/services/T000/B000/XXX", Events: []hook.Event{"OnCheckFailed"}, Source: "prod", Channel: "#alerts"}
+	r := send(t, s, checkPayload(failingCheck()), requests)
+
+	if r.method != "POST" || r.path != "/services/T000/B000/XXX" {
+		t.Errorf("request %s %s, want POST /services/T000/B000/XXX", r.method, r.path)
+	}
+	if ct := r.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %s, want application/json", ct)
+	}
+
+	var msg slackMessage
+	decode(t, r.body, &msg)
+	if msg.Text != "[prod] dns is failing" {
+		t.Errorf("text = %q", msg.Text)
+	}
+	if msg.Channel != "#alerts" {
+		t.Errorf("channel = %q, want #alerts", msg.Channel)
+	}
+	if len(msg.Blocks) != 3 {
+		t.Fatalf("got %d blocks, want a title, the check and the run: %s", len(msg.Blocks), r.body)
+	}
+	if got := msg.Blocks[0].Text.Text; got != ":red_circle: *[prod] dns is failing*" {
+		t.Errorf("title block = %q", got)
+	}
+	check := msg.Blocks[1].Text.Text
+	for _, want := range []string{"*dns*", "lookup failed", "platform", "<https://runbooks.example.com/dns|Runbook>", "records Equals: expected 1, got 0"} {
+		if !strings.Contains(check, want) {
+			t.Errorf("check block %q does not contain %q", check, want)
+		}
+	}
+	if got := msg.Blocks[2].Elements[0].Text; got != "Run run-1, 0 passed, 1 failed, 0 disabled" {
+		t.Errorf("run block = %q", got)
+	}
+}
+
+func TestSlackCheckRecovered(t *testing.T) {
+	server, requests := newReceiver(t)
+	defer server.Close()
+
+	s := Slack{Name: "slack", URL: server.URL, Events: []hook.Event{"OnCheckRecovered"}}
+	p := checkPayload(recoveredCheck())
+	r := send(t, s, p, requests)
+
+	var msg slackMessage
+	decode(t, r.body, &msg)
+	if msg.Text != "[kubecheck] dns recovered after 10m0s" {
+		t.Errorf("text = %q", msg.Text)
+	}
+	if len(msg.Blocks) != 2 || !strings.HasPrefix(msg.Blocks[0].Text.Text, ":large_green_circle:") {
+		t.Errorf("blocks = %s, want a green title and the run", r.body)
+	}
+}
+
+func TestSlackEscape(t *testing.T) {
+	if got := slackEscape("<a & b>"); got != "&lt;a &amp; b&gt;" {
+		t.Errorf("slackEscape() = %q", got)
+	}
+}
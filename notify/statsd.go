@@ -0,0 +1,187 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+const (
+	defaultStatsDAddress = "127.0.0.1:8125"
+	defaultStatsDPrefix  = "kubecheck"
+	// maxStatsDPacket keeps packets within the MTU of most networks
+	maxStatsDPacket = 1432
+)
+
+// StatsD sends the status and duration of each check as StatsD metrics over UDP when a run has completed.
+// With DogStatsD the check is a tag, e.g. kubecheck.check.success:1|g|#check:dns, otherwise it is part of the metric name,
+// e.g. kubecheck.check.dns.success:1|g.
+type StatsD struct {
+	Name string
+	// Address of the StatsD server or agent as host:port. Defaults to 127.0.0.1:8125.
+	Address string
+	// Prefix of the metric names. Defaults to kubecheck.
+	Prefix string
+	// DogStatsD tags the metrics with the check, its severity and team instead of naming metrics after checks
+	DogStatsD bool
+	// Tags are added to all metrics with DogStatsD
+	Tags map[string]string
+}
+
+var invalidStatsDChars = regexp.MustCompile(`[^a-zA-Z0-9_\-]`)
+
+var statsDTagEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+// Describe returns the description of the notifier
+func (s StatsD) Describe() hook.NotifierDescription {
+	return hook.NotifierDescription{Name: s.Name, Type: "statsd"}
+}
+
+// Subscribes returns whether the event is the completion of a run, which is the only event sent
+func (s StatsD) Subscribes(e hook.Event) bool {
+	return e == completedEvent
+}
+
+// Validate checks that the notifier has a name and a valid address
+func (s StatsD) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("statsd notifier has no name")
+	}
+	if _, _, err := net.SplitHostPort(s.address()); err != nil {
+		return fmt.Errorf("statsd notifier \"%s\" has an invalid address: %v", s.Name, err)
+	}
+	return nil
+}
+
+// Prepare creates the metrics of a completed run. Partial runs are not sent, since their run metrics only count some of the checks.
+func (s StatsD) Prepare(p hook.Payload) (hook.Message, error) {
+	if p.Run.Partial || (p.Run.Status != checks.Passed && p.Run.Status != checks.Failed) {
+		return nil, nil
+	}
+
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = defaultStatsDPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, ".") + "."
+
+	lines := make([]string, 0)
+	add := func(name string, value float64, kind string, tags []string) {
+		line := prefix + name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + kind
+		if s.DogStatsD {
+			tags = append(tags, s.constantTags()...)
+			if len(tags) > 0 {
+				line += "|#" + strings.Join(tags, ",")
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	for _, cr := range p.Results {
+		if cr.Status != checks.Passed && cr.Status != checks.Failed {
+			continue
+		}
+		success := boolValue(cr.Status == checks.Passed)
+		duration := float64(cr.Duration) / float64(time.Millisecond)
+		if s.DogStatsD {
+			tags := []string{statsDTag("check", cr.Name)}
+			if cr.Severity != "" {
+				tags = append(tags, statsDTag("severity", cr.Severity))
+			}
+			if cr.Owner.Team != "" {
+				tags = append(tags, statsDTag("team", cr.Owner.Team))
+			}
+			add("check.success", success, "g", tags)
+			add("check.duration", duration, "ms", tags)
+			continue
+		}
+		name := invalidStatsDChars.ReplaceAllString(cr.Name, "_")
+		add("check."+name+".success", success, "g", nil)
+		add("check."+name+".duration", duration, "ms", nil)
+	}
+
+	add("run.success", boolValue(p.Run.Status == checks.Passed), "g", nil)
+	add("run.duration", float64(p.Run.Duration)/float64(time.Millisecond), "ms", nil)
+	add("run.passed", float64(p.Run.Passed), "g", nil)
+	add("run.failed", float64(p.Run.Failed), "g", nil)
+	add("run.disabled", float64(p.Run.Disabled), "g", nil)
+
+	return &statsDMessage{address: s.address(), lines: lines}, nil
+}
+
+func (s StatsD) address() string {
+	if s.Address == "" {
+		return defaultStatsDAddress
+	}
+	return s.Address
+}
+
+// constantTags returns the tags added to all metrics, ordered by name
+func (s StatsD) constantTags() []string {
+	tags := make([]string, 0, len(s.Tags))
+	for k, v := range s.Tags {
+		tags = append(tags, statsDTag(k, v))
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func statsDTag(name string, value string) string {
+	return statsDTagEscaper.Replace(name) + ":" + statsDTagEscaper.Replace(value)
+}
+
+// statsDMessage defines metrics prepared for delivery
+type statsDMessage struct {
+	address string
+	lines   []string
+}
+
+// Send writes the metrics to the StatsD server in as few packets as possible.
+// UDP does not report whether the metrics were received.
+func (m *statsDMessage) Send(ctx context.Context, timeout time.Duration) (int, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "udp", m.address)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	packet := ""
+	for _, line := range m.lines {
+		if packet != "" && len(packet)+1+len(line) > maxStatsDPacket {
+			if _, err := conn.Write([]byte(packet)); err != nil {
+				return 0, err
+			}
+			packet = ""
+		}
+		if packet != "" {
+			packet += "\n"
+		}
+		packet += line
+	}
+	if packet != "" {
+		if _, err := conn.Write([]byte(packet)); err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+// Destination returns the address of the StatsD server
+func (m *statsDMessage) Destination() string {
+	return "udp://" + m.address
+}
+
+// Content returns the metrics
+func (m *statsDMessage) Content() []byte {
+	return []byte(strings.Join(m.lines, "\n"))
+}
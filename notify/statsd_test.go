@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenUDP starts a UDP listener for StatsD packets. The listener must be closed by the caller.
+func listenUDP(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	return conn
+}
+
+// sendStatsD sends the metrics of a completed run and returns the packets received by the listener
+func sendStatsD(t *testing.T, s StatsD, conn *net.UDPConn) []string {
+	t.Helper()
+
+	s.Address = conn.LocalAddr().String()
+	msg, err := s.Prepare(completedPayload())
+	if err != nil || msg == nil {
+		t.Fatalf("Prepare() = %v, %v", msg, err)
+	}
+	if _, err := msg.Send(context.Background(), time.Second); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	packets := make([]string, 0)
+	buf := make([]byte, 65536)
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, err := conn.Read(buf)
+		if err != nil {
+			break
+		}
+		packets = append(packets, string(buf[:n]))
+	}
+	return packets
+}
+
+func TestStatsD(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	packets := sendStatsD(t, StatsD{Name: "statsd", Prefix: "k8s.kubecheck."}, conn)
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets, want 1: %q", len(packets), packets)
+	}
+
+	want := []string{
+		"k8s.kubecheck.check.api.success:1|g",
+		"k8s.kubecheck.check.api.duration:250|ms",
+		"k8s.kubecheck.check.dns.success:0|g",
+		"k8s.kubecheck.check.dns.duration:1500|ms",
+		"k8s.kubecheck.run.success:0|g",
+		"k8s.kubecheck.run.duration:2000|ms",
+		"k8s.kubecheck.run.passed:1|g",
+		"k8s.kubecheck.run.failed:1|g",
+		"k8s.kubecheck.run.disabled:0|g",
+	}
+	if got := strings.Split(packets[0], "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("metrics =\n%s\nwant\n%s", packets[0], strings.Join(want, "\n"))
+	}
+}
+
+func TestDogStatsD(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	packets := sendStatsD(t, StatsD{Name: "datadog", DogStatsD: true, Tags: map[string]string{"env": "prod", "cluster": "eu,1"}}, conn)
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets, want 1: %q", len(packets), packets)
+	}
+
+	lines := strings.Split(packets[0], "\n")
+	for _, want := range []string{
+		"kubecheck.check.success:1|g|#check:api,cluster:eu_1,env:prod",
+		"kubecheck.check.success:0|g|#check:dns,severity:critical,team:platform,cluster:eu_1,env:prod",
+		"kubecheck.check.duration:1500|ms|#check:dns,severity:critical,team:platform,cluster:eu_1,env:prod",
+		"kubecheck.run.success:0|g|#cluster:eu_1,env:prod",
+	} {
+		if !containsLine(lines, want) {
+			t.Errorf("metrics %q do not contain %q", lines, want)
+		}
+	}
+}
+
+func TestStatsDSplitsPackets(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	tags := make(map[string]string)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		tags[name] = strings.Repeat(name, 40)
+	}
+	packets := sendStatsD(t, StatsD{Name: "datadog", DogStatsD: true, Tags: tags}, conn)
+	if len(packets) < 2 {
+		t.Fatalf("got %d packets, want the metrics split", len(packets))
+	}
+
+	lines := 0
+	for _, p := range packets {
+		if len(p) > maxStatsDPacket {
+			t.Errorf("packet of %d bytes exceeds %d", len(p), maxStatsDPacket)
+		}
+		lines += len(strings.Split(p, "\n"))
+	}
+	if lines != 9 {
+		t.Errorf("got %d metrics, want 9", lines)
+	}
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStatsDSkipsPartialRuns(t *testing.T) {
+	p := completedPayload()
+	p.Run.Partial = true
+	skipped(t, StatsD{Name: "statsd"}, p)
+}
@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+// Teams posts Adaptive Cards listing the failed checks and assertions to a Microsoft Teams incoming webhook or workflow
+type Teams struct {
+	Name string
+	// URL of the incoming webhook. It may reference environment variables like ${TEAMS_WEBHOOK_URL}.
+	URL    string
+	Events []hook.Event
+	// Source identifies this instance in messages, e.g. the cluster name. Defaults to kubecheck.
+	Source string
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+}
+
+type teamsElement struct {
+	Type     string      `json:"type"`
+	Text     string      `json:"text,omitempty"`
+	Weight   string      `json:"weight,omitempty"`
+	Size     string      `json:"size,omitempty"`
+	Color    string      `json:"color,omitempty"`
+	IsSubtle bool        `json:"isSubtle,omitempty"`
+	Wrap     bool        `json:"wrap,omitempty"`
+	Facts    []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Describe returns the description of the notifier
+func (t Teams) Describe() hook.NotifierDescription {
+	return hook.NotifierDescription{Name: t.Name, Type: "teams"}
+}
+
+// Subscribes returns whether the notifier handles the event
+func (t Teams) Subscribes(e hook.Event) bool {
+	return subscribes(t.Events, e)
+}
+
+// Validate checks that the notifier has a name, url and events
+func (t Teams) Validate() error {
+	return validate("teams", t.Name, t.URL, t.Events)
+}
+
+// Prepare creates the card for the payload
+func (t Teams) Prepare(p hook.Payload) (hook.Message, error) {
+	url, err := hook.ExpandEnv(t.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	color := "Good"
+	if failed(p) {
+		color = "Attention"
+	}
+
+	body := []teamsElement{
+		{Type: "TextBlock", Text: title(sourceOrDefault(t.Source), p), Weight: "Bolder", Size: "Medium", Color: color, Wrap: true},
+	}
+
+	listed := reported(p)
+	for i, c := range listed {
+		if i == maxChecks {
+			body = append(body, teamsElement{Type: "TextBlock", Text: more(len(listed), maxChecks, "checks"), IsSubtle: true, Wrap: true})
+			break
+		}
+
+		name := c.Name
+		if p.Event == groupedEvent {
+			name += " " + change(c)
+		}
+		body = append(body, teamsElement{Type: "TextBlock", Text: name, Weight: "Bolder", Wrap: true})
+		if c.Reason != "" {
+			body = append(body, teamsElement{Type: "TextBlock", Text: c.Reason, Wrap: true})
+		}
+		if o := c.Owner; !o.IsZero() {
+			text := owner(o)
+			if o.RunbookURL != "" {
+				text = owner(checks.Owner{Team: o.Team, Contact: o.Contact}) + " [Runbook](" + o.RunbookURL + ")"
+			}
+			body = append(body, teamsElement{Type: "TextBlock", Text: strings.TrimSpace(text), IsSubtle: true, Wrap: true})
+		}
+
+		facts := make([]teamsFact, 0)
+		for j, a := range c.Assertions {
+			if j == maxAssertions {
+				facts = append(facts, teamsFact{Title: "...", Value: more(len(c.Assertions), maxAssertions, "failed assertions")})
+				break
+			}
+			name := a.Type
+			if a.Group != "" {
+				name = a.Group + " " + name
+			}
+			facts = append(facts, teamsFact{Title: name, Value: fmt.Sprintf("expected %s, got %s", a.Expected, a.Actual)})
+		}
+		if len(facts) > 0 {
+			body = append(body, teamsElement{Type: "FactSet", Facts: facts})
+		}
+	}
+
+	body = append(body, teamsElement{
+		Type:     "TextBlock",
+		Text:     fmt.Sprintf("Run %s, %d passed, %d failed, %d disabled", p.Run.ID, p.Run.Passed, p.Run.Failed, p.Run.Disabled),
+		IsSubtle: true,
+		Wrap:     true,
+	})
+
+	msg := teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: teamsCard{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.4",
+					Body:    body,
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hook.Request{
+		Method:      "POST",
+		URL:         url,
+		ContentType: "application/json",
+		Body:        data,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+func TestTeamsCompleted(t *testing.T) {
+	server, requests := newReceiver(t)
+	defer server.Close()
+
+	n := Teams{Name: "teams", URL: server.URL + "/webhook", Events: []hook.Event{completedEvent}, Source: "prod"}
+	r := send(t, n, completedPayload(), requests)
+
+	var msg teamsMessage
+	decode(t, r.body, &msg)
+	if msg.Type != "message" || len(msg.Attachments) != 1 {
+		t.Fatalf("message = %s, want a single attachment", r.body)
+	}
+	card := msg.Attachments[0]
+	if card.ContentType != "application/vnd.microsoft.card.adaptive" || card.Content.Type != "AdaptiveCard" {
+		t.Errorf("attachment = %+v, want an adaptive card", card)
+	}
+
+	body := card.Content.Body
+	want := []teamsElement{
+		{Type: "TextBlock", Text: "[prod] 1 of 2 checks failed", Weight: "Bolder", Size: "Medium", Color: "Attention", Wrap: true},
+		{Type: "TextBlock", Text: "dns", Weight: "Bolder", Wrap: true},
+		{Type: "TextBlock", Text: "lookup failed", Wrap: true},
+		{Type: "TextBlock", Text: "Owner: platform [Runbook](https://runbooks.example.com/dns)", IsSubtle: true, Wrap: true},
+	}
+	if len(body) != len(want)+2 {
+		t.Fatalf("got %d elements, want %d: %s", len(body), len(want)+2, r.body)
+	}
+	for i, e := range want {
+		if body[i].Type != e.Type || body[i].Text != e.Text || body[i].Color != e.Color || body[i].IsSubtle != e.IsSubtle {
+			t.Errorf("element %d = %+v, want %+v", i, body[i], e)
+		}
+	}
+	facts := body[len(want)].Facts
+	if len(facts) != 1 || facts[0].Title != "records Equals" || facts[0].Value != "expected 1, got 0" {
+		t.Errorf("facts = %+v", facts)
+	}
+	if got := body[len(body)-1].Text; got != "Run run-1, 1 passed, 1 failed, 0 disabled" {
+		t.Errorf("run = %q", got)
+	}
+}
@@ -0,0 +1,193 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces redacted values
+const Mask = "****"
+
+// DefaultFields are the field and header names that are always redacted
+var DefaultFields = []string{
+	"authorization",
+	"proxy-authorization",
+	"cookie",
+	"set-cookie",
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"api-key",
+	"apikey",
+	"private-key",
+	"credentials",
+}
+
+// DefaultQueryParams are the query-string keys that are always redacted in URLs
+var DefaultQueryParams = []string{
+	"token",
+	"access_token",
+	"api_key",
+	"apikey",
+	"key",
+	"password",
+	"secret",
+	"signature",
+	"sig",
+	"code",
+}
+
+// DefaultPatterns are the patterns that are always redacted in strings
+var DefaultPatterns = []string{
+	`(?i)\b(bearer|basic)\s+[a-z0-9._~+/=-]+`,
+}
+
+// Redactor masks secrets in healthcheck data
+type Redactor struct {
+	fields      []string
+	queryParams map[string]bool
+	patterns    []*regexp.Regexp
+}
+
+var defaultRedactor, _ = New(nil, nil, nil)
+
+// Default returns a redactor using only the default fields, query parameters and patterns
+func Default() *Redactor {
+	return defaultRedactor
+}
+
+// New creates a redactor for the given field names, query parameters and regular expressions in addition to the defaults.
+// Field names are matched case-insensitively and ignoring "-" and "_", also as a suffix, so "token" matches "X-Auth-Token".
+func New(fields []string, queryParams []string, patterns []string) (*Redactor, error) {
+	r := &Redactor{
+		queryParams: make(map[string]bool),
+	}
+
+	for _, f := range append(append([]string{}, DefaultFields...), fields...) {
+		r.fields = append(r.fields, normalize(f))
+	}
+
+	for _, q := range append(append([]string{}, DefaultQueryParams...), queryParams...) {
+		r.queryParams[strings.ToLower(q)] = true
+	}
+
+	for _, p := range append(append([]string{}, DefaultPatterns...), patterns...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern \"%s\": %v", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+func normalize(name string) string {
+	name = strings.ToLower(name)
+	name = strings.Replace(name, "-", "", -1)
+	return strings.Replace(name, "_", "", -1)
+}
+
+// IsSensitive returns whether values of the named field should be redacted
+func (r *Redactor) IsSensitive(field string) bool {
+	name := normalize(field)
+	for _, f := range r.fields {
+		if f != "" && strings.HasSuffix(name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks credentials and sensitive query parameters of URLs and matches of the patterns in s
+func (r *Redactor) String(s string) string {
+	if strings.Contains(s, "://") {
+		s = urlPattern.ReplaceAllStringFunc(s, r.url)
+	}
+
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Mask)
+	}
+
+	return s
+}
+
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+func (r *Redactor) url(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+
+	changed := false
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Mask)
+		changed = true
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			if r.queryParams[strings.ToLower(key)] || r.IsSensitive(key) {
+				query.Set(key, Mask)
+				changed = true
+			}
+		}
+		if changed {
+			u.RawQuery = query.Encode()
+		}
+	}
+
+	if !changed {
+		return s
+	}
+	return strings.Replace(u.String(), url.QueryEscape(Mask), Mask, -1)
+}
+
+// Value returns a copy of v with secrets masked. Structs are converted to their JSON representation,
+// so the result encodes to the same JSON as v apart from the redacted values.
+func (r *Redactor) Value(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return r.String(fmt.Sprintf("%v", v))
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return r.String(string(data))
+	}
+
+	return r.walk(generic)
+}
+
+func (r *Redactor) walk(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if r.IsSensitive(key) && item != nil {
+				value[key] = Mask
+				continue
+			}
+			value[key] = r.walk(item)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = r.walk(item)
+		}
+		return value
+	case string:
+		return r.String(value)
+	default:
+		return value
+	}
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+
+	"github.com/apex/log"
+	"github.com/gorilla/mux"
+)
+
+// disabledCheck describes why and until when a healthcheck is disabled
+type disabledCheck struct {
+	Reason     string     `json:"reason"`
+	DisabledAt time.Time  `json:"disabledAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+// acknowledgedCheck describes who is working on a failing healthcheck
+type acknowledgedCheck struct {
+	Author         string    `json:"author"`
+	Comment        string    `json:"comment,omitempty"`
+	AcknowledgedAt time.Time `json:"acknowledgedAt"`
+}
+
+// checkStates keeps track of disabled and acknowledged healthchecks.
+// Acknowledgements are kept per scope like transitions, for the scopes in which the check was failing when it was acknowledged.
+type checkStates struct {
+	mu           sync.Mutex
+	disabled     map[string]disabledCheck
+	acknowledged map[transitionKey]acknowledgedCheck
+}
+
+type disableCheckRequest struct {
+	Reason    string     `json:"reason"`
+	ExpiresIn string     `json:"expiresIn"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type acknowledgeCheckRequest struct {
+	Author  string `json:"author"`
+	Comment string `json:"comment"`
+}
+
+type apiAdminCheckResponse struct {
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	Enabled      bool               `json:"enabled"`
+	Disabled     *disabledCheck     `json:"disabled,omitempty"`
+	Acknowledged *acknowledgedCheck `json:"acknowledged,omitempty"`
+}
+
+func newCheckStates() *checkStates {
+	return &checkStates{
+		disabled:     make(map[string]disabledCheck),
+		acknowledged: make(map[transitionKey]acknowledgedCheck),
+	}
+}
+
+// get returns the disabled state of the named healthcheck, enabling it again once expired
+func (s *checkStates) get(name string) (disabledCheck, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dc, ok := s.disabled[name]
+	if ok && dc.ExpiresAt != nil && time.Now().After(*dc.ExpiresAt) {
+		delete(s.disabled, name)
+		return disabledCheck{}, false
+	}
+	return dc, ok
+}
+
+func (s *checkStates) disable(name string, dc disabledCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disabled[name] = dc
+}
+
+func (s *checkStates) enable(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.disabled, name)
+}
+
+// acknowledgement returns the acknowledgement of the named healthcheck in any scope, or nil if it is not acknowledged
+func (s *checkStates) acknowledgement(name string) *acknowledgedCheck {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, ac := range s.acknowledged {
+		if key.name == name {
+			return &ac
+		}
+	}
+	return nil
+}
+
+// scopedAcknowledgement returns the acknowledgement of the named healthcheck in a scope, or nil if it is not acknowledged there
+func (s *checkStates) scopedAcknowledgement(scope string, name string) *acknowledgedCheck {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ac, ok := s.acknowledged[transitionKey{scope: scope, name: name}]
+	if !ok {
+		return nil
+	}
+	return &ac
+}
+
+// acknowledge acknowledges the named healthcheck in the scopes
+func (s *checkStates) acknowledge(name string, scopes []string, ac acknowledgedCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, scope := range scopes {
+		s.acknowledged[transitionKey{scope: scope, name: name}] = ac
+	}
+}
+
+// unacknowledge clears the acknowledgement of the named healthcheck in all scopes
+func (s *checkStates) unacknowledge(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.acknowledged {
+		if key.name == name {
+			delete(s.acknowledged, key)
+		}
+	}
+}
+
+// recovered clears the acknowledgement of the named healthcheck in a scope and returns whether it was acknowledged there
+func (s *checkStates) recovered(scope string, name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := transitionKey{scope: scope, name: name}
+	_, ok := s.acknowledged[key]
+	delete(s.acknowledged, key)
+	return ok
+}
+
+func registerAdminRoutes(router *mux.Router, kubecheck *config.Kubecheck, m *monitor) {
+	if kubecheck.Config.Admin.Token == "" {
+		return
+	}
+
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(adminAuthMiddleware(kubecheck.Config.Admin.Token))
+	admin.HandleFunc("/checks", adminListChecksHandler(kubecheck, m)).Methods("GET")
+	admin.HandleFunc("/checks/{name}/disable", adminDisableCheckHandler(kubecheck, m)).Methods("POST")
+	admin.HandleFunc("/checks/{name}/enable", adminEnableCheckHandler(kubecheck, m)).Methods("POST")
+	admin.HandleFunc("/checks/{name}/acknowledge", adminAcknowledgeCheckHandler(kubecheck, m)).Methods("POST")
+	admin.HandleFunc("/checks/{name}/unacknowledge", adminUnacknowledgeCheckHandler(kubecheck, m)).Methods("POST")
+	admin.HandleFunc("/checks/{name}/run", adminRunCheckHandler(kubecheck, m)).Methods("POST")
+
+	// Deliveries show excerpts of their errors, so they are only listed with the token of the admin API
+	router.Handle("/hooks", adminAuthMiddleware(kubecheck.Config.Admin.Token)(http.HandlerFunc(hooksHandler(m)))).Methods("GET")
+}
+
+func adminAuthMiddleware(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="kubecheck"`)
+				writeError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func adminListChecksHandler(kubecheck *config.Kubecheck, m *monitor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := make([]apiAdminCheckResponse, 0)
+		for _, c := range kubecheck.Healthchecks {
+			response = append(response, newAPIAdminCheckResponse(m, c))
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func adminDisableCheckHandler(kubecheck *config.Kubecheck, m *monitor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := findHealthcheck(kubecheck.Healthchecks, mux.Vars(r)["name"])
+		if c == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("healthcheck not found"))
+			return
+		}
+
+		request := disableCheckRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if request.Reason == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("a reason is required"))
+			return
+		}
+
+		dc := disabledCheck{
+			Reason:     request.Reason,
+			DisabledAt: time.Now(),
+			ExpiresAt:  request.ExpiresAt,
+		}
+
+		if request.ExpiresIn != "" {
+			d, err := time.ParseDuration(request.ExpiresIn)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			expiresAt := dc.DisabledAt.Add(d)
+			dc.ExpiresAt = &expiresAt
+		}
+
+		name := c.Describe().Name
+		m.states.disable(name, dc)
+
+		log.WithFields(log.Fields{
+			"service":   "Admin",
+			"name":      name,
+			"reason":    dc.Reason,
+			"expiresAt": dc.ExpiresAt,
+		}).Info("disabled healthcheck")
+
+		writeJSON(w, http.StatusOK, newAPIAdminCheckResponse(m, c))
+	}
+}
+
+func adminEnableCheckHandler(kubecheck *config.Kubecheck, m *monitor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := findHealthcheck(kubecheck.Healthchecks, mux.Vars(r)["name"])
+		if c == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("healthcheck not found"))
+			return
+		}
+
+		name := c.Describe().Name
+		m.states.enable(name)
+
+		log.WithFields(log.Fields{
+			"service": "Admin",
+			"name":    name,
+		}).Info("enabled healthcheck")
+
+		writeJSON(w, http.StatusOK, newAPIAdminCheckResponse(m, c))
+	}
+}
+
+func adminAcknowledgeCheckHandler(kubecheck *config.Kubecheck, m *monitor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := findHealthcheck(kubecheck.Healthchecks, mux.Vars(r)["name"])
+		if c == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("healthcheck not found"))
+			return
+		}
+
+		request := acknowledgeCheckRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if request.Author == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("an author is required"))
+			return
+		}
+
+		name := c.Describe().Name
+		scopes := m.transitions.failingScopes(name)
+		if len(scopes) == 0 {
+			writeError(w, http.StatusConflict, fmt.Errorf("healthcheck is not failing"))
+			return
+		}
+
+		ac := acknowledgedCheck{
+			Author:         request.Author,
+			Comment:        request.Comment,
+			AcknowledgedAt: time.Now(),
+		}
+		m.states.acknowledge(name, scopes, ac)
+
+		log.WithFields(log.Fields{
+			"service": "Admin",
+			"name":    name,
+			"scopes":  scopes,
+			"author":  ac.Author,
+			"comment": ac.Comment,
+		}).Info("acknowledged healthcheck")
+
+		writeJSON(w, http.StatusOK, newAPIAdminCheckResponse(m, c))
+	}
+}
+
+func adminUnacknowledgeCheckHandler(kubecheck *config.Kubecheck, m *monitor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := findHealthcheck(kubecheck.Healthchecks, mux.Vars(r)["name"])
+		if c == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("healthcheck not found"))
+			return
+		}
+
+		name := c.Describe().Name
+		m.states.unacknowledge(name)
+
+		log.WithFields(log.Fields{
+			"service": "Admin",
+			"name":    name,
+		}).Info("cleared healthcheck acknowledgement")
+
+		writeJSON(w, http.StatusOK, newAPIAdminCheckResponse(m, c))
+	}
+}
+
+func adminRunCheckHandler(kubecheck *config.Kubecheck, m *monitor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := findHealthcheck(kubecheck.Healthchecks, mux.Vars(r)["name"])
+		if c == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("healthcheck not found"))
+			return
+		}
+
+		results := m.runHealtchecks(context.Background(), kubecheck.Config, []checks.Healthcheck{c}, runOptions{partial: partialRun(kubecheck.Healthchecks, []checks.Healthcheck{c})}, func(d checks.Description, r checks.Result) interface{} {
+			return newAPICheckResponse(kubecheck.Config, m, d, r)
+		})
+
+		writeJSON(w, http.StatusOK, results)
+	}
+}
+
+func newAPIAdminCheckResponse(m *monitor, c checks.Healthcheck) apiAdminCheckResponse {
+	d := c.Describe()
+	response := apiAdminCheckResponse{
+		Name:        d.Name,
+		Description: d.Description,
+		Enabled:     true,
+	}
+
+	if dc, ok := m.states.get(d.Name); ok {
+		response.Enabled = false
+		response.Disabled = &dc
+	}
+	response.Acknowledged = m.states.acknowledgement(d.Name)
+
+	return response
+}
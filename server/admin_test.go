@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+// adminRequest performs an authorized request against the admin API
+func adminRequest(router http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// runScope runs a check in a scope, the instance or a group
+func runScope(m *monitor, cfg *config.KubecheckConfig, scope string, hc checks.Healthcheck) {
+	m.runHealtchecks(context.Background(), cfg, []checks.Healthcheck{hc}, runOptions{scope: scope}, func(d checks.Description, r checks.Result) interface{} {
+		return r
+	})
+}
+
+// reminders returns the number of OnCheckStillFailing deliveries
+func reminders(m *monitor) int {
+	count := 0
+	for _, d := range m.hooks.Recent() {
+		if d.Event == config.OnCheckStillFailingEvent {
+			count++
+		}
+	}
+	return count
+}
+
+func TestAcknowledgementScopes(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	failing := staticCheck{name: "dns", status: checks.Failed}
+	passing := staticCheck{name: "dns", status: checks.Passed}
+	cfg := &config.KubecheckConfig{
+		Admin:         config.AdminConfig{Token: "secret"},
+		Notifications: config.NotificationsConfig{ReminderInterval: time.Nanosecond},
+		Webhooks: []hook.Webhook{
+			{Name: "reminders", URL: receiver.URL, Events: []hook.Event{config.OnCheckStillFailingEvent}, Data: "{}"},
+		},
+	}
+	router, m, _ := newTestRouter(&config.Kubecheck{Config: cfg, Healthchecks: []checks.Healthcheck{failing}})
+
+	if w := adminRequest(router, "POST", "/admin/checks/dns/acknowledge", `{"author": "jane"}`); w.Code != http.StatusConflict {
+		t.Fatalf("acknowledging a passing check = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	runScope(m, cfg, "", failing)
+	runScope(m, cfg, "core", failing)
+	if w := adminRequest(router, "POST", "/admin/checks/dns/acknowledge", `{"author": "jane"}`); w.Code != http.StatusOK {
+		t.Fatalf("acknowledging a failing check = %d: %s", w.Code, w.Body)
+	}
+
+	// Acknowledged checks do not remind in any scope they are failing in
+	runScope(m, cfg, "", failing)
+	runScope(m, cfg, "core", failing)
+	if got := reminders(m); got != 0 {
+		t.Errorf("got %d reminders of the acknowledged check, want 0", got)
+	}
+
+	// Recovering in a group clears the acknowledgement of the group only
+	runScope(m, cfg, "core", passing)
+	if m.states.scopedAcknowledgement("core", "dns") != nil || m.states.scopedAcknowledgement("", "dns") == nil {
+		t.Errorf("acknowledgements of core = %v and the instance = %v, want only the instance acknowledged",
+			m.states.scopedAcknowledgement("core", "dns"), m.states.scopedAcknowledgement("", "dns"))
+	}
+	if ac := m.states.acknowledgement("dns"); ac == nil || ac.Author != "jane" {
+		t.Errorf("acknowledgement = %v, want the check still acknowledged while it fails for the instance", ac)
+	}
+
+	// Failing again in the group reminds, since the new failure is not acknowledged
+	runScope(m, cfg, "core", failing)
+	runScope(m, cfg, "core", failing)
+	runScope(m, cfg, "", failing)
+	if got := reminders(m); got != 1 {
+		t.Errorf("got %d reminders, want 1 of the group", got)
+	}
+
+	runScope(m, cfg, "", passing)
+	if ac := m.states.acknowledgement("dns"); ac != nil {
+		t.Errorf("acknowledgement = %v, want it cleared once the check recovered in every acknowledged scope", ac)
+	}
+
+	if err := m.hooks.Close(context.Background()); err != nil {
+		t.Errorf("Close() error: %v", err)
+	}
+}
+
+func TestUnacknowledge(t *testing.T) {
+	failing := staticCheck{name: "dns", status: checks.Failed}
+	cfg := &config.KubecheckConfig{Admin: config.AdminConfig{Token: "secret"}}
+	router, m, _ := newTestRouter(&config.Kubecheck{Config: cfg, Healthchecks: []checks.Healthcheck{failing}})
+
+	runScope(m, cfg, "", failing)
+	runScope(m, cfg, "core", failing)
+	adminRequest(router, "POST", "/admin/checks/dns/acknowledge", `{"author": "jane"}`)
+
+	if w := adminRequest(router, "POST", "/admin/checks/dns/unacknowledge", ""); w.Code != http.StatusOK {
+		t.Fatalf("unacknowledge = %d", w.Code)
+	}
+	if m.states.scopedAcknowledgement("", "dns") != nil || m.states.scopedAcknowledgement("core", "dns") != nil {
+		t.Error("unacknowledge did not clear all scopes")
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+)
+
+const defaultBadgeMaxAge = time.Minute
+
+// Badge colors
+const (
+	badgeColorPassed  = "#4c1"
+	badgeColorFailed  = "#e05d44"
+	badgeColorUnknown = "#9f9f9f"
+)
+
+type badge struct {
+	Label        string
+	Message      string
+	Color        string
+	LabelWidth   int
+	MessageWidth int
+}
+
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{html .Label}}: {{html .Message}}">
+<title>{{html .Label}}: {{html .Message}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/><rect width="{{.Width}}" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Label}}</text><text x="{{.LabelX}}" y="14">{{html .Label}}</text>
+<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Message}}</text><text x="{{.MessageX}}" y="14">{{html .Message}}</text>
+</g>
+</svg>
+`))
+
+func newBadge(label string, status string) badge {
+	color := badgeColorUnknown
+	switch status {
+	case checks.Passed:
+		color = badgeColorPassed
+	case checks.Failed:
+		color = badgeColorFailed
+	}
+
+	return badge{
+		Label:        label,
+		Message:      status,
+		Color:        color,
+		LabelWidth:   badgeTextWidth(label),
+		MessageWidth: badgeTextWidth(status),
+	}
+}
+
+// Width returns the total width of the badge
+func (b badge) Width() int {
+	return b.LabelWidth + b.MessageWidth
+}
+
+// LabelX returns the horizontal center of the label
+func (b badge) LabelX() int {
+	return b.LabelWidth / 2
+}
+
+// MessageX returns the horizontal center of the message
+func (b badge) MessageX() int {
+	return b.LabelWidth + b.MessageWidth/2
+}
+
+// badgeTextWidth approximates the rendered width of text in Verdana 11px including padding
+func badgeTextWidth(text string) int {
+	return len([]rune(text))*7 + 10
+}
+
+func badgeHandler(m *monitor, config *config.KubecheckConfig, healthchecks []checks.Healthcheck, label string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		maxAge := config.API.BadgeMaxAge
+		if maxAge == 0 {
+			maxAge = defaultBadgeMaxAge
+		}
+
+		status := checks.Passed
+		stale := make([]checks.Healthcheck, 0)
+		disabled := 0
+
+		for _, hc := range healthchecks {
+			if _, ok := m.states.get(hc.Describe().Name); ok {
+				disabled++
+				continue
+			}
+
+			cr, ok := m.results.get(hc.Describe().Name, maxAge)
+			if !ok {
+				stale = append(stale, hc)
+				continue
+			}
+			if cr.Result.Status == checks.Failed {
+				status = checks.Failed
+			}
+		}
+
+		if len(stale) > 0 {
+			m.runHealtchecks(context.Background(), config, stale, runOptions{silent: true}, func(d checks.Description, r checks.Result) interface{} {
+				if r.Status == checks.Failed {
+					status = checks.Failed
+				}
+				return nil
+			})
+		}
+
+		if len(healthchecks) > 0 && disabled == len(healthchecks) {
+			status = checks.Disabled
+		}
+
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.WriteHeader(http.StatusOK)
+
+		badgeTemplate.Execute(w, newBadge(label, status))
+	}
+}
+
+func getAllBadgePath() string {
+	return fmt.Sprintf("/badges/%s.svg", config.AllChecksBadge)
+}
+
+func getBadgePath(healthcheck checks.Healthcheck) string {
+	name := healthcheck.Describe().Name
+	return fmt.Sprintf("/badges/%s.svg", url.PathEscape(name))
+}
+
+func getGroupBadgePath(g config.Group) string {
+	return fmt.Sprintf("/badges/groups/%s.svg", url.PathEscape(g.Name))
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+)
+
+func TestBadgePaths(t *testing.T) {
+	kubecheck := &config.Kubecheck{
+		Config: &config.KubecheckConfig{},
+		Healthchecks: []checks.Healthcheck{
+			staticCheck{name: "api", status: checks.Passed},
+			staticCheck{name: "dns-lookup", status: checks.Failed},
+		},
+		Groups: []config.Group{
+			{Name: "core", Checks: []string{"api"}},
+			{Name: "edge", Checks: []string{"dns-lookup"}},
+		},
+	}
+	router, _, _ := newTestRouter(kubecheck)
+
+	tests := map[string]string{
+		"/badges/all.svg":         "kubecheck: failed",
+		"/badges/api.svg":         "api: passed",
+		"/badges/dns-lookup.svg":  "dns-lookup: failed",
+		"/badges/groups/core.svg": "core: passed",
+		"/badges/groups/edge.svg": "edge: failed",
+	}
+	for path, label := range tests {
+		w := serve(t, router, "GET", path, nil)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/svg+xml" {
+			t.Errorf("GET %s = %d %s, want an SVG", path, w.Code, w.Header().Get("Content-Type"))
+			continue
+		}
+		if !strings.Contains(w.Body.String(), `aria-label="`+label+`"`) {
+			t.Errorf("GET %s = %s, want the badge %q", path, w.Body, label)
+		}
+	}
+
+	for _, path := range []string{"/badge.svg", "/groups/core/badge.svg", "/badges/groups/unknown.svg"} {
+		if w := serve(t, router, "GET", path, nil); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestBadgeDisabled(t *testing.T) {
+	kubecheck := &config.Kubecheck{
+		Config:       &config.KubecheckConfig{Admin: config.AdminConfig{Token: "secret"}},
+		Healthchecks: []checks.Healthcheck{staticCheck{name: "dns", status: checks.Failed}},
+	}
+	router, _, _ := newTestRouter(kubecheck)
+
+	if w := adminRequest(router, "POST", "/admin/checks/dns/disable", `{"reason": "flapping"}`); w.Code != http.StatusOK {
+		t.Fatalf("disable = %d: %s", w.Code, w.Body)
+	}
+	if w := serve(t, router, "GET", "/badges/all.svg", nil); !strings.Contains(w.Body.String(), `aria-label="kubecheck: disabled"`) {
+		t.Errorf("GET /badges/all.svg = %s, want the badge of disabled checks", w.Body)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+)
+
+type apiClusterResponse struct {
+	Status string                              `json:"status"`
+	Reason string                              `json:"reason,omitempty"`
+	Checks map[string]checks.RemoteCheckResult `json:"checks"`
+}
+
+// federationHealthchecks creates a remote healthcheck for each peer, keyed by healthcheck name
+func federationHealthchecks(peers []config.Peer) ([]checks.Healthcheck, map[string]config.Peer) {
+	hcks := make([]checks.Healthcheck, 0)
+	byName := make(map[string]config.Peer)
+
+	for _, p := range peers {
+		// Prefixed to keep remote results apart from local healthchecks with the same name
+		name := "federation/" + p.Cluster
+		hcks = append(hcks, checks.RemoteKubecheckHealthcheck{
+			Name:        name,
+			Description: "Imports the results of the kubecheck instance in cluster " + p.Cluster,
+			URL:         p.URL,
+			Checks:      p.Checks,
+			Timeout:     p.Timeout,
+		})
+		byName[name] = p
+	}
+
+	return hcks, byName
+}
+
+func federationHandler(m *monitor, cfg *config.KubecheckConfig) func(w http.ResponseWriter, r *http.Request) {
+	hcks, peers := federationHealthchecks(cfg.Federation.Peers)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var mu sync.Mutex
+		statusCode := http.StatusOK
+		response := make(map[string]apiClusterResponse)
+
+		// Peers are checked concurrently, so the response takes as long as the slowest peer, which is bounded by its timeout
+		var wg sync.WaitGroup
+		for _, hc := range hcks {
+			wg.Add(1)
+			go func(hc checks.Healthcheck) {
+				defer wg.Done()
+
+				// Federation runs are not recorded as local checks and do not trigger the webhooks and notifiers of this instance
+				m.runHealtchecks(context.Background(), cfg, []checks.Healthcheck{hc}, runOptions{ephemeral: true}, func(d checks.Description, r checks.Result) interface{} {
+					cr := apiClusterResponse{
+						Status: r.Status,
+						Reason: r.Reason,
+						Checks: remoteResults(r.Output),
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+					if r.Status == checks.Failed {
+						statusCode = failedStatusCode(cfg)
+					}
+					response[peers[d.Name].Cluster] = cr
+					return cr
+				})
+			}(hc)
+		}
+		wg.Wait()
+
+		if cfg.API.ForceOKStatusCode {
+			statusCode = http.StatusOK
+		}
+
+		writeJSON(w, statusCode, response)
+	}
+}
+
+// remoteResults returns the results imported by a remote healthcheck from its output,
+// which is a generic map once the result has been redacted
+func remoteResults(output interface{}) map[string]checks.RemoteCheckResult {
+	if results, ok := output.(map[string]checks.RemoteCheckResult); ok {
+		return results
+	}
+
+	results := make(map[string]checks.RemoteCheckResult)
+	data, err := json.Marshal(output)
+	if err != nil {
+		return results
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return make(map[string]checks.RemoteCheckResult)
+	}
+	return results
+}
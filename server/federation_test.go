@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+	"github.com/StenaIT/kubecheck/hook"
+	"github.com/StenaIT/kubecheck/store"
+)
+
+func newTestMonitor() *monitor {
+	return newMonitor(store.NewMemoryStore(), hook.NewDispatcher(hook.DeliveryConfig{}))
+}
+
+func TestFederationUnreachablePeer(t *testing.T) {
+	done := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer hanging.Close()
+	defer close(done)
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]checks.RemoteCheckResult{
+			"dns": {Status: checks.Passed},
+		})
+	}))
+	defer healthy.Close()
+
+	cfg := &config.KubecheckConfig{
+		Federation: config.FederationConfig{
+			Peers: []config.Peer{
+				{Cluster: "down", URL: hanging.URL, Checks: []string{"a", "b", "c"}, Timeout: 200 * time.Millisecond},
+				{Cluster: "up", URL: healthy.URL, Timeout: 200 * time.Millisecond},
+			},
+		},
+	}
+	m := newTestMonitor()
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	federationHandler(m, cfg)(w, httptest.NewRequest("GET", "/federation/", nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("response took %s, want the peers to share one deadline", elapsed)
+	}
+
+	if w.Code != http.StatusFailedDependency {
+		t.Errorf("status code = %d, want %d", w.Code, http.StatusFailedDependency)
+	}
+
+	response := make(map[string]apiClusterResponse)
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response %s: %v", w.Body, err)
+	}
+	if down := response["down"]; down.Status != checks.Failed || !strings.Contains(down.Reason, "unreachable") {
+		t.Errorf("down = %+v, want an unreachable failed cluster", down)
+	}
+	if up := response["up"]; up.Status != checks.Passed || up.Checks["dns"].Status != checks.Passed {
+		t.Errorf("up = %+v, want a passed cluster with dns", up)
+	}
+
+	if records, _ := m.history.Query("", time.Time{}, time.Now()); len(records) != 0 {
+		t.Errorf("history has %d records, want federation runs not to be recorded", len(records))
+	}
+}
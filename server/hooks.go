@@ -0,0 +1,30 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+type apiHooksResponse struct {
+	Deliveries []hook.DeliveryStatus `json:"deliveries"`
+}
+
+// hooksHandler lists the recent webhook deliveries, optionally filtered by ?hook= and ?status=
+func hooksHandler(m *monitor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("hook")
+		status := r.URL.Query().Get("status")
+
+		response := apiHooksResponse{
+			Deliveries: make([]hook.DeliveryStatus, 0),
+		}
+		for _, d := range m.hooks.Recent() {
+			if (name == "" || d.Hook == name) && (status == "" || d.Status == status) {
+				response.Deliveries = append(response.Deliveries, d)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
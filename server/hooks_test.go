@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/StenaIT/kubecheck/config"
+)
+
+func TestHooksRequireAdminToken(t *testing.T) {
+	router, _, _ := newTestRouter(&config.Kubecheck{Config: &config.KubecheckConfig{Admin: config.AdminConfig{Token: "secret"}}})
+
+	tests := []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/hooks", nil)
+		if tt.auth != "" {
+			r.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("GET /hooks with %q = %d, want %d", tt.auth, w.Code, tt.want)
+		}
+	}
+
+	router, _, _ = newTestRouter(&config.Kubecheck{Config: &config.KubecheckConfig{}})
+	if w := serve(t, router, "GET", "/hooks", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET /hooks without an admin token = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -1,32 +1,142 @@
 package server
 
 import (
+	"context"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/StenaIT/kubecheck/checks"
 	conf "github.com/StenaIT/kubecheck/config"
 	"github.com/StenaIT/kubecheck/hook"
+	"github.com/StenaIT/kubecheck/logging"
+	"github.com/StenaIT/kubecheck/redact"
+	"github.com/StenaIT/kubecheck/store"
 
 	"github.com/apex/log"
 )
 
+// monitor executes healthchecks and keeps state shared between runs
+type monitor struct {
+	results     *resultCache
+	states      *checkStates
+	transitions *checkTransitions
+	history     store.Store
+	hooks       *hook.Dispatcher
+	inflight    sync.WaitGroup
+}
+
+// cachedResult is the latest known result of a healthcheck
+type cachedResult struct {
+	Description checks.Description
+	Result      checks.Result
+	Time        time.Time
+}
+
+// resultCache keeps the latest result of each healthcheck
+type resultCache struct {
+	mu      sync.RWMutex
+	results map[string]cachedResult
+}
+
+func newMonitor(history store.Store, hooks *hook.Dispatcher) *monitor {
+	return &monitor{
+		history: history,
+		hooks:   hooks,
+		results: &resultCache{
+			results: make(map[string]cachedResult),
+		},
+		states:      newCheckStates(),
+		transitions: newCheckTransitions(),
+	}
+}
+
+// runOptions defines how a run is notified
+type runOptions struct {
+	// scope is the group the run is for, or empty for the instance. Status changes are tracked per scope.
+	scope string
+	// silent runs do not trigger webhooks and notifiers, and do not change the status tracked for per-check events
+	silent bool
+	// partial runs leave out some of the checks of the instance or group they run for
+	partial bool
+	// ephemeral runs are silent and are not recorded in the result cache and history either
+	ephemeral bool
+}
+
 // TODO: Run checks async
-func runHealtchecks(config *conf.KubecheckConfig, healthchecks []checks.Healthcheck, resultMapper func(d checks.Description, r checks.Result) interface{}) map[string]interface{} {
+func (m *monitor) runHealtchecks(ctx context.Context, config *conf.KubecheckConfig, healthchecks []checks.Healthcheck, opts runOptions, resultMapper func(d checks.Description, r checks.Result) interface{}) map[string]interface{} {
+	m.inflight.Add(1)
+	defer m.inflight.Done()
+
 	results := make(map[string]interface{})
+	redactor := newRedactor(config.Redaction)
 
-	hook.TriggerWebhooks(config.Webhooks, conf.OnHealthcheckStartedEvent)
+	runID, ok := runIDFromContext(ctx)
+	if !ok {
+		runID = newRunID()
+	}
+	rl := log.WithFields(log.Fields{
+		"service": "Checks",
+		"run":     runID,
+	})
+
+	payload := hook.Payload{
+		Event: conf.OnHealthcheckStartedEvent,
+		Run: hook.RunSummary{
+			ID:        runID,
+			Status:    "running",
+			Total:     len(healthchecks),
+			StartedAt: time.Now(),
+			Group:     opts.scope,
+			Partial:   opts.partial,
+		},
+		Results: make([]hook.CheckResult, 0),
+		Failed:  make([]hook.CheckResult, 0),
+		Changes: make([]hook.CheckResult, 0),
+	}
+
+	// Checks and webhooks are not cancelled with the run, so the check being executed is allowed to finish
+	hookCtx := logging.NewContext(context.Background(), rl)
+	subscribers := config.Subscribers()
+	if opts.silent || opts.ephemeral {
+		subscribers = nil
+	}
+	m.hooks.Enqueue(hookCtx, subscribers, payload)
 
 	for _, check := range healthchecks {
-		typeName, _ := NameOf(check)
+		if ctx.Err() != nil {
+			rl.WithError(ctx.Err()).Warn("healthcheck run cancelled")
+			payload.Run.Partial = true
+			break
+		}
+
+		typeName, _ := NameOf(checks.Unwrap(check))
 		d := check.Describe()
-		result := check.Execute()
+		cl := rl.WithFields(log.Fields{
+			"type": typeName,
+			"name": d.Name,
+		})
+
+		if dc, ok := m.states.get(d.Name); ok {
+			cl.WithFields(log.Fields{
+				"reason": dc.Reason,
+			}).Debug("skipping disabled healthcheck")
+
+			disabled := checks.Result{Status: checks.Disabled, Reason: dc.Reason}
+			payload.Add(hook.NewCheckResult(d, disabled))
+			results[d.Name] = resultMapper(d, disabled)
+			continue
+		}
+
+		start := time.Now()
+		result := redactResult(redactor, checks.Execute(logging.NewContext(context.Background(), cl), check))
+		duration := time.Since(start)
 
-		l := log.WithFields(log.Fields{
-			"type":        typeName,
-			"name":        d.Name,
+		l := cl.WithFields(log.Fields{
 			"description": d.Description,
 			"status":      result.Status,
 			"reason":      result.Reason,
+			"duration":    duration,
 			"input":       result.Input,
 			"output":      result.Output,
 		})
@@ -37,14 +147,139 @@ func runHealtchecks(config *conf.KubecheckConfig, healthchecks []checks.Healthch
 			l.Debug("finished executing healthcheck")
 		}
 
+		cr := hook.NewCheckResult(d, result)
+		cr.Duration = duration
+		payload.Add(cr)
+
+		if opts.ephemeral {
+			results[d.Name] = resultMapper(d, result)
+			continue
+		}
+
+		m.results.set(d, result)
+
+		if result.Status == checks.Passed && m.states.recovered(opts.scope, d.Name) {
+			cl.Info("cleared acknowledgement of recovered healthcheck")
+		}
+
+		err := m.history.Append(store.Record{
+			Check:    d.Name,
+			Status:   result.Status,
+			Reason:   result.Reason,
+			Time:     start,
+			Duration: duration,
+			Input:    result.Input,
+			Output:   result.Output,
+		})
+		if err != nil {
+			cl.WithError(err).Warn("failed to store healthcheck result")
+		}
 		results[d.Name] = resultMapper(d, result)
+
+		if opts.silent {
+			continue
+		}
+
+		event, since := m.transitions.observe(opts.scope, d.Name, result.Status, time.Now(), config.Notifications.ReminderInterval)
+		if ac := m.states.scopedAcknowledgement(opts.scope, d.Name); ac != nil && event == conf.OnCheckStillFailingEvent {
+			cl.WithFields(log.Fields{
+				"author": ac.Author,
+			}).Debug("skipping reminder of acknowledged healthcheck")
+			event = ""
+		}
+
+		if event != "" {
+			cr.FailingSince = since
+			cr.Event = event
+			payload.Changes = append(payload.Changes, cr)
+			checkPayload := payload
+			checkPayload.Event = event
+			checkPayload.Check = &cr
+			m.hooks.Enqueue(logging.NewContext(context.Background(), cl), subscribers, checkPayload)
+			m.hooks.Group(hookCtx, opts.scope, subscribers, conf.OnChecksChangedEvent, checkPayload, config.Notifications.GroupWait)
+		}
 	}
 
-	hook.TriggerWebhooks(config.Webhooks, conf.OnHealthcheckCompletedEvent)
+	payload.Event = conf.OnHealthcheckCompletedEvent
+	payload.Complete(time.Now())
+	m.hooks.Enqueue(hookCtx, subscribers, payload)
+
+	if payload.Run.Failed > 0 {
+		payload.Event = conf.OnRunFailedEvent
+		m.hooks.Enqueue(hookCtx, subscribers, payload)
+	}
 
 	return results
 }
 
+type runIDKey struct{}
+
+// withRunID returns a context carrying the ID of a run, which is used to correlate its log entries
+func withRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+func runIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(runIDKey{}).(string)
+	return id, ok
+}
+
+func newRedactor(c conf.RedactionConfig) *redact.Redactor {
+	r, err := redact.New(c.Fields, c.QueryParams, c.Patterns)
+	if err != nil {
+		log.WithError(err).Warn("invalid redaction config, using the defaults")
+		return redact.Default()
+	}
+	return r
+}
+
+// redactResult masks secrets in a result before it is logged, returned, stored or sent to webhooks
+func redactResult(r *redact.Redactor, result checks.Result) checks.Result {
+	result.Reason = r.String(result.Reason)
+	result.Input = r.Value(result.Input)
+	result.Output = r.Value(result.Output)
+	return result
+}
+
+// wait blocks until all healthcheck runs have finished or the context is done
+func (m *monitor) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// get returns the latest result of the named healthcheck if it is younger than maxAge
+func (c *resultCache) get(name string, maxAge time.Duration) (cachedResult, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cr, ok := c.results[name]
+	if !ok || time.Since(cr.Time) > maxAge {
+		return cachedResult{}, false
+	}
+	return cr, true
+}
+
+func (c *resultCache) set(d checks.Description, r checks.Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results[d.Name] = cachedResult{
+		Description: d,
+		Result:      r,
+		Time:        time.Now(),
+	}
+}
+
 // NameOf returns the name and type for types and pointers
 func NameOf(i interface{}) (string, reflect.Type) {
 	t := reflect.TypeOf(i)
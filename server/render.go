@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/StenaIT/kubecheck/checks"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Response formats
+const (
+	formatJSON     = "json"
+	formatText     = "text"
+	formatYAML     = "yaml"
+	formatMarkdown = "markdown"
+)
+
+var formatsByName = map[string]string{
+	"json":     formatJSON,
+	"text":     formatText,
+	"txt":      formatText,
+	"yaml":     formatYAML,
+	"yml":      formatYAML,
+	"markdown": formatMarkdown,
+	"md":       formatMarkdown,
+}
+
+var formatsByMediaType = map[string]string{
+	"application/json":   formatJSON,
+	"text/plain":         formatText,
+	"application/yaml":   formatYAML,
+	"application/x-yaml": formatYAML,
+	"text/yaml":          formatYAML,
+	"text/x-yaml":        formatYAML,
+	"text/markdown":      formatMarkdown,
+	"text/x-markdown":    formatMarkdown,
+}
+
+var contentTypes = map[string]string{
+	formatJSON:     "application/json",
+	formatText:     "text/plain; charset=utf-8",
+	formatYAML:     "application/yaml",
+	formatMarkdown: "text/markdown; charset=utf-8",
+}
+
+// negotiateFormat selects the response format from the format query parameter or the Accept header.
+// JSON is used if nothing else is acceptable.
+func negotiateFormat(r *http.Request) (string, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		format, ok := formatsByName[strings.ToLower(name)]
+		if !ok {
+			return "", fmt.Errorf("unknown format \"%s\", expected json, text, yaml or markdown", name)
+		}
+		return format, nil
+	}
+
+	best, bestQ := formatJSON, 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		format, ok := formatsByMediaType[mediaType]
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+
+	return best, nil
+}
+
+// writeResults writes healthcheck results in the format negotiated with the client, see negotiateFormat
+func writeResults(w http.ResponseWriter, format string, statusCode int, results map[string]interface{}) {
+	if format == formatJSON {
+		w.Header().Set("Vary", "Accept")
+		writeJSON(w, statusCode, results)
+		return
+	}
+
+	var body []byte
+	var err error
+	switch format {
+	case formatYAML:
+		body, err = yaml.Marshal(results)
+	case formatText:
+		body = renderText(results)
+	case formatMarkdown:
+		body = renderMarkdown(results)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Vary", "Accept")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// sortedResults returns the check responses ordered by name
+func sortedResults(results map[string]interface{}) ([]string, map[string]apiCheckResponse) {
+	names := make([]string, 0, len(results))
+	responses := make(map[string]apiCheckResponse)
+	for name, v := range results {
+		if response, ok := v.(apiCheckResponse); ok {
+			names = append(names, name)
+			responses[name] = response
+		}
+	}
+	sort.Strings(names)
+	return names, responses
+}
+
+func summarize(names []string, responses map[string]apiCheckResponse) (string, int) {
+	failed := 0
+	for _, name := range names {
+		if responses[name].Status == checks.Failed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return checks.Failed, failed
+	}
+	return checks.Passed, failed
+}
+
+func renderText(results map[string]interface{}) []byte {
+	names, responses := sortedResults(results)
+	status, failed := summarize(names, responses)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s: %d of %d checks failed\n\n", strings.ToUpper(status), failed, len(names))
+
+	tw := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		response := responses[name]
+		fmt.Fprintf(tw, "%s\t%s\t%s%s\n", strings.ToUpper(response.Status), name, response.Reason, acknowledgement(response))
+	}
+	tw.Flush()
+
+	for _, name := range names {
+		response := responses[name]
+		if response.Status != checks.Failed {
+			continue
+		}
+		assertions := checks.FailedAssertions(response.Output)
+		if len(assertions) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "\n%s:\n", name)
+		for _, a := range assertions {
+			fmt.Fprintf(buf, "  - %s %s: expected %s, got %s\n", a.Group, a.Type, a.Expected, a.Actual)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func renderMarkdown(results map[string]interface{}) []byte {
+	names, responses := sortedResults(results)
+	status, failed := summarize(names, responses)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "## Kubecheck: %s\n\n", status)
+	fmt.Fprintf(buf, "%d of %d checks failed.\n\n", failed, len(names))
+	fmt.Fprintf(buf, "| Check | Status | Reason |\n|---|---|---|\n")
+	for _, name := range names {
+		response := responses[name]
+		s := response.Status
+		if s == checks.Failed {
+			s = "**" + s + "**"
+		}
+		fmt.Fprintf(buf, "| %s | %s | %s |\n", markdownCell(name), s, markdownCell(response.Reason+acknowledgement(response)))
+	}
+
+	for _, name := range names {
+		response := responses[name]
+		if response.Status != checks.Failed {
+			continue
+		}
+
+		fmt.Fprintf(buf, "\n### %s\n\n", markdownCell(name))
+		if response.Description != "" {
+			fmt.Fprintf(buf, "%s\n\n", markdownCell(response.Description))
+		}
+
+		assertions := checks.FailedAssertions(response.Output)
+		if len(assertions) == 0 {
+			fmt.Fprintf(buf, "%s\n", markdownCell(response.Reason))
+			continue
+		}
+
+		fmt.Fprintf(buf, "| Assertion | Type | Expected | Actual |\n|---|---|---|---|\n")
+		for _, a := range assertions {
+			fmt.Fprintf(buf, "| %s | %s | `%s` | `%s` |\n", markdownCell(a.Group), markdownCell(a.Type), markdownCode(a.Expected), markdownCode(a.Actual))
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// acknowledgement describes who acknowledged a failing check, appended to its reason
+func acknowledgement(response apiCheckResponse) string {
+	ac := response.Acknowledged
+	if ac == nil {
+		return ""
+	}
+	if ac.Comment == "" {
+		return fmt.Sprintf(" (acknowledged by %s)", ac.Author)
+	}
+	return fmt.Sprintf(" (acknowledged by %s: %s)", ac.Author, ac.Comment)
+}
+
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\r", " ", "\n", " ")
+
+func markdownCell(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+func markdownCode(s string) string {
+	return strings.Replace(markdownCell(s), "`", "'", -1)
+}
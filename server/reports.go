@@ -0,0 +1,117 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StenaIT/kubecheck/config"
+	"github.com/StenaIT/kubecheck/store"
+)
+
+const (
+	defaultReportWindow = 24 * time.Hour
+	defaultSLO          = 99.9
+)
+
+type apiReportsResponse struct {
+	From    time.Time               `json:"from"`
+	To      time.Time               `json:"to"`
+	Reports map[string]store.Report `json:"reports"`
+}
+
+func reportsHandler(kubecheck *config.Kubecheck, m *monitor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		from, to, err := parseReportWindow(query.Get("from"), query.Get("to"), query.Get("window"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		slo := kubecheck.Config.History.SLO
+		if slo == 0 {
+			slo = defaultSLO
+		}
+		if s := query.Get("slo"); s != "" {
+			slo, err = strconv.ParseFloat(s, 64)
+			if err != nil || slo < 0 || slo >= 100 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("slo must be a percentage below 100"))
+				return
+			}
+		}
+
+		names := query["check"]
+		if len(names) == 0 {
+			for _, hc := range kubecheck.Healthchecks {
+				names = append(names, hc.Describe().Name)
+			}
+		}
+
+		response := apiReportsResponse{
+			From:    from,
+			To:      to,
+			Reports: make(map[string]store.Report),
+		}
+
+		for _, name := range names {
+			records, err := m.history.Query(name, from, to)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			response.Reports[name] = store.NewReport(name, records, from, to, slo)
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// parseReportWindow parses RFC 3339 timestamps or a window relative to now like "12h" or "7d"
+func parseReportWindow(fromParam string, toParam string, windowParam string) (time.Time, time.Time, error) {
+	to := time.Now()
+	if toParam != "" {
+		t, err := time.Parse(time.RFC3339, toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %v", err)
+		}
+		to = t
+	}
+
+	if fromParam != "" {
+		from, err := time.Parse(time.RFC3339, fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %v", err)
+		}
+		if !from.Before(to) {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+		}
+		return from, to, nil
+	}
+
+	window := defaultReportWindow
+	if windowParam != "" {
+		w, err := parseDays(windowParam)
+		if err != nil || w <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid window \"%s\"", windowParam)
+		}
+		window = w
+	}
+
+	return to.Add(-window), to, nil
+}
+
+// parseDays parses a duration that may be given in days, like "30d"
+func parseDays(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	conf "github.com/StenaIT/kubecheck/config"
+
+	"github.com/gorilla/mux"
+)
+
+// Run states
+const (
+	runRunning   = "running"
+	runCompleted = "completed"
+	runCancelled = "cancelled"
+)
+
+// maxFinishedRuns is the number of finished runs kept in memory
+const maxFinishedRuns = 100
+
+type createRunRequest struct {
+	Checks []string `json:"checks"`
+}
+
+type apiRunResponse struct {
+	ID         string                      `json:"id"`
+	URL        string                      `json:"url"`
+	Status     string                      `json:"status"`
+	Result     string                      `json:"result,omitempty"`
+	Progress   apiRunProgress              `json:"progress"`
+	StartedAt  time.Time                   `json:"startedAt"`
+	FinishedAt *time.Time                  `json:"finishedAt,omitempty"`
+	Results    map[string]apiCheckResponse `json:"results"`
+}
+
+type apiRunProgress struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+}
+
+// run is a single asynchronous execution of one or more healthchecks
+type run struct {
+	mu         sync.Mutex
+	id         string
+	status     string
+	failed     bool
+	total      int
+	startedAt  time.Time
+	finishedAt time.Time
+	results    map[string]apiCheckResponse
+	cancel     context.CancelFunc
+}
+
+// runManager starts and keeps track of asynchronous runs
+type runManager struct {
+	mu           sync.Mutex
+	monitor      *monitor
+	config       *conf.KubecheckConfig
+	healthchecks []checks.Healthcheck
+	runs         map[string]*run
+	finished     []string
+}
+
+func newRunManager(monitor *monitor, config *conf.KubecheckConfig, healthchecks []checks.Healthcheck) *runManager {
+	return &runManager{
+		monitor:      monitor,
+		config:       config,
+		healthchecks: healthchecks,
+		runs:         make(map[string]*run),
+		finished:     make([]string, 0),
+	}
+}
+
+// update replaces the config and healthchecks used for new runs
+func (m *runManager) update(config *conf.KubecheckConfig, healthchecks []checks.Healthcheck) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = config
+	m.healthchecks = healthchecks
+}
+
+// start begins executing the named healthchecks, or all of them if none are given
+func (m *runManager) start(names []string) (*run, error) {
+	m.mu.Lock()
+	config := m.config
+	healthchecks, err := selectHealthchecks(m.healthchecks, names)
+	partial := partialRun(m.healthchecks, healthchecks)
+	m.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	id := newRunID()
+	ctx, cancel := context.WithCancel(withRunID(context.Background(), id))
+	rn := &run{
+		id:        id,
+		status:    runRunning,
+		total:     len(healthchecks),
+		startedAt: time.Now(),
+		results:   make(map[string]apiCheckResponse),
+		cancel:    cancel,
+	}
+
+	m.mu.Lock()
+	m.runs[rn.id] = rn
+	m.mu.Unlock()
+
+	// The run is in flight before it starts, so that a shutdown in between waits for it
+	m.monitor.inflight.Add(1)
+	go func() {
+		defer m.monitor.inflight.Done()
+		defer cancel()
+
+		m.monitor.runHealtchecks(ctx, config, healthchecks, runOptions{partial: partial}, func(d checks.Description, r checks.Result) interface{} {
+			response := newAPICheckResponse(config, m.monitor, d, r)
+			rn.mu.Lock()
+			rn.results[d.Name] = response
+			if r.Status == checks.Failed {
+				rn.failed = true
+			}
+			rn.mu.Unlock()
+			return response
+		})
+
+		rn.mu.Lock()
+		if ctx.Err() != nil {
+			rn.status = runCancelled
+		} else {
+			rn.status = runCompleted
+		}
+		rn.finishedAt = time.Now()
+		rn.mu.Unlock()
+
+		m.finish(rn.id)
+	}()
+
+	return rn, nil
+}
+
+// get returns the run with the given id
+func (m *runManager) get(id string) (*run, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rn, ok := m.runs[id]
+	return rn, ok
+}
+
+// cancelAll cancels all runs that are still running
+func (m *runManager) cancelAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rn := range m.runs {
+		rn.cancel()
+	}
+}
+
+// finish records a finished run and evicts the oldest finished runs
+func (m *runManager) finish(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.finished = append(m.finished, id)
+	for len(m.finished) > maxFinishedRuns {
+		delete(m.runs, m.finished[0])
+		m.finished = m.finished[1:]
+	}
+}
+
+// selectHealthchecks returns the named healthchecks once each, or all of them if none are named
+func selectHealthchecks(healthchecks []checks.Healthcheck, names []string) ([]checks.Healthcheck, error) {
+	if len(names) == 0 {
+		return healthchecks, nil
+	}
+
+	selected := make([]checks.Healthcheck, 0)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		hc := findHealthcheck(healthchecks, name)
+		if hc == nil {
+			return nil, fmt.Errorf("unknown healthcheck \"%s\"", name)
+		}
+		selected = append(selected, hc)
+	}
+
+	return selected, nil
+}
+
+// partialRun returns whether the selected healthchecks leave out any of all healthchecks
+func partialRun(all []checks.Healthcheck, selected []checks.Healthcheck) bool {
+	names := make(map[string]bool)
+	for _, hc := range selected {
+		names[hc.Describe().Name] = true
+	}
+	for _, hc := range all {
+		if !names[hc.Describe().Name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (rn *run) response(r *http.Request) apiRunResponse {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	response := apiRunResponse{
+		ID:     rn.id,
+		URL:    generateBaseURL(r) + "/runs/" + rn.id,
+		Status: rn.status,
+		Progress: apiRunProgress{
+			Total:     rn.total,
+			Completed: len(rn.results),
+		},
+		StartedAt: rn.startedAt,
+		Results:   make(map[string]apiCheckResponse),
+	}
+
+	for name, result := range rn.results {
+		response.Results[name] = result
+	}
+
+	if rn.status != runRunning {
+		finishedAt := rn.finishedAt
+		response.FinishedAt = &finishedAt
+	}
+
+	if rn.status == runCompleted {
+		response.Result = checks.Passed
+		if rn.failed {
+			response.Result = checks.Failed
+		}
+	}
+
+	return response
+}
+
+func findHealthcheck(healthchecks []checks.Healthcheck, name string) checks.Healthcheck {
+	for _, hc := range healthchecks {
+		if hc.Describe().Name == name {
+			return hc
+		}
+	}
+	return nil
+}
+
+func newRunID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func createRunHandler(runs *runManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := createRunRequest{}
+
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		for _, name := range r.URL.Query()["check"] {
+			request.Checks = append(request.Checks, name)
+		}
+
+		rn, err := runs.start(request.Checks)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		response := rn.response(r)
+		w.Header().Set("Location", response.URL)
+		writeJSON(w, http.StatusAccepted, response)
+	}
+}
+
+func getRunHandler(runs *runManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rn, ok := runs.get(mux.Vars(r)["id"])
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("run not found"))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, rn.response(r))
+	}
+}
+
+func cancelRunHandler(runs *runManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rn, ok := runs.get(mux.Vars(r)["id"])
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("run not found"))
+			return
+		}
+
+		rn.cancel()
+
+		writeJSON(w, http.StatusAccepted, rn.response(r))
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+)
+
+// staticCheck is a stand-in healthcheck with a fixed status
+type staticCheck struct {
+	name   string
+	status string
+}
+
+func (c staticCheck) Describe() checks.Description {
+	return checks.Description{Name: c.name}
+}
+
+func (c staticCheck) Execute() checks.Result {
+	return checks.Result{Status: c.status, Reason: c.status}
+}
+
+// blockingCheck is a stand-in healthcheck that passes once released
+type blockingCheck struct {
+	name    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingCheck(name string) blockingCheck {
+	return blockingCheck{name: name, started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (c blockingCheck) Describe() checks.Description {
+	return checks.Description{Name: c.name}
+}
+
+func (c blockingCheck) Execute() checks.Result {
+	c.started <- struct{}{}
+	<-c.release
+	return checks.Result{Status: checks.Passed}
+}
+
+// newTestRouter creates the router of a kubecheck context with an in-memory history
+func newTestRouter(kubecheck *config.Kubecheck) (http.Handler, *monitor, *runManager) {
+	m := newTestMonitor()
+	runs := newRunManager(m, kubecheck.Config, kubecheck.Healthchecks)
+	return newRouter(kubecheck, m, runs), m, runs
+}
+
+// serve performs a request against the router and decodes the JSON response into v if set
+func serve(t *testing.T, router http.Handler, method, url string, v interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: invalid response %s: %v", method, url, w.Body, err)
+		}
+	}
+	return w
+}
+
+// waitInflight waits at most for the timeout for the in-flight runs of the monitor
+func waitInflight(m *monitor, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return m.wait(ctx)
+}
+
+// poll gets a run until it is no longer running
+func poll(t *testing.T, router http.Handler, id string) apiRunResponse {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		response := apiRunResponse{}
+		if w := serve(t, router, "GET", "/runs/"+id, &response); w.Code != http.StatusOK {
+			t.Fatalf("GET /runs/%s = %d", id, w.Code)
+		}
+		if response.Status != runRunning || time.Now().After(deadline) {
+			return response
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunCompletes(t *testing.T) {
+	kubecheck := &config.Kubecheck{
+		Config: &config.KubecheckConfig{},
+		Healthchecks: []checks.Healthcheck{
+			staticCheck{name: "api", status: checks.Passed},
+			staticCheck{name: "dns", status: checks.Failed},
+		},
+	}
+	router, m, _ := newTestRouter(kubecheck)
+
+	started := apiRunResponse{}
+	w := serve(t, router, "POST", "/runs", &started)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("POST /runs = %d, want %d", w.Code, http.StatusAccepted)
+	}
+	if started.ID == "" || w.Header().Get("Location") != started.URL || !strings.HasSuffix(started.URL, "/runs/"+started.ID) {
+		t.Errorf("started run = %+v, Location = %s", started, w.Header().Get("Location"))
+	}
+	if started.Progress.Total != 2 {
+		t.Errorf("total = %d, want 2", started.Progress.Total)
+	}
+
+	finished := poll(t, router, started.ID)
+	if finished.Status != runCompleted || finished.Result != checks.Failed || finished.FinishedAt == nil {
+		t.Errorf("finished run = %+v, want a completed failed run", finished)
+	}
+	if finished.Progress.Completed != 2 || finished.Results["api"].Status != checks.Passed || finished.Results["dns"].Status != checks.Failed {
+		t.Errorf("results = %+v", finished.Results)
+	}
+
+	if err := waitInflight(m, time.Second); err != nil {
+		t.Errorf("wait() error: %v", err)
+	}
+}
+
+func TestRunSelectedChecks(t *testing.T) {
+	kubecheck := &config.Kubecheck{
+		Config: &config.KubecheckConfig{},
+		Healthchecks: []checks.Healthcheck{
+			staticCheck{name: "api", status: checks.Passed},
+			staticCheck{name: "dns", status: checks.Failed},
+		},
+	}
+	router, _, _ := newTestRouter(kubecheck)
+
+	started := apiRunResponse{}
+	if w := serve(t, router, "POST", "/runs?check=api&check=api", &started); w.Code != http.StatusAccepted {
+		t.Fatalf("POST /runs = %d, want %d", w.Code, http.StatusAccepted)
+	}
+	if started.Progress.Total != 1 {
+		t.Errorf("total = %d, want duplicate names to count once", started.Progress.Total)
+	}
+
+	finished := poll(t, router, started.ID)
+	if finished.Result != checks.Passed || finished.Progress.Completed != 1 || len(finished.Results) != 1 {
+		t.Errorf("finished run = %+v, want only api", finished)
+	}
+
+	if w := serve(t, router, "POST", "/runs?check=unknown", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("POST /runs of an unknown check = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	blocking := newBlockingCheck("slow")
+	kubecheck := &config.Kubecheck{
+		Config: &config.KubecheckConfig{},
+		Healthchecks: []checks.Healthcheck{
+			blocking,
+			staticCheck{name: "api", status: checks.Passed},
+		},
+	}
+	router, _, _ := newTestRouter(kubecheck)
+
+	started := apiRunResponse{}
+	serve(t, router, "POST", "/runs", &started)
+	<-blocking.started
+
+	cancelled := apiRunResponse{}
+	if w := serve(t, router, "DELETE", "/runs/"+started.ID, &cancelled); w.Code != http.StatusAccepted {
+		t.Fatalf("DELETE /runs/%s = %d, want %d", started.ID, w.Code, http.StatusAccepted)
+	}
+	close(blocking.release)
+
+	finished := poll(t, router, started.ID)
+	if finished.Status != runCancelled || finished.Result != "" {
+		t.Errorf("finished run = %+v, want a cancelled run", finished)
+	}
+	if _, ok := finished.Results["api"]; ok || finished.Results["slow"].Status != checks.Passed {
+		t.Errorf("results = %+v, want the started check to finish and the next to be skipped", finished.Results)
+	}
+}
+
+func TestRunUnknown(t *testing.T) {
+	router, _, _ := newTestRouter(&config.Kubecheck{Config: &config.KubecheckConfig{}})
+
+	for _, method := range []string{"GET", "DELETE"} {
+		response := apiErrorResponse{}
+		if w := serve(t, router, method, "/runs/unknown", &response); w.Code != http.StatusNotFound || response.Error == "" {
+			t.Errorf("%s /runs/unknown = %d %+v, want %d", method, w.Code, response, http.StatusNotFound)
+		}
+	}
+}
+
+func TestRunInflightBeforeStart(t *testing.T) {
+	blocking := newBlockingCheck("slow")
+	kubecheck := &config.Kubecheck{
+		Config:       &config.KubecheckConfig{},
+		Healthchecks: []checks.Healthcheck{blocking},
+	}
+	_, m, runs := newTestRouter(kubecheck)
+
+	if _, err := runs.start(nil); err != nil {
+		t.Fatalf("start() error: %v", err)
+	}
+
+	// The run counts as in flight as soon as start returns, even if its goroutine has not been scheduled yet
+	if err := waitInflight(m, 50*time.Millisecond); err == nil {
+		t.Errorf("wait() returned while the run was in flight")
+	}
+
+	close(blocking.release)
+	if err := waitInflight(m, time.Second); err != nil {
+		t.Errorf("wait() error: %v", err)
+	}
+}
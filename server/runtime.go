@@ -0,0 +1,431 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+	"github.com/StenaIT/kubecheck/hook"
+	"github.com/StenaIT/kubecheck/store"
+
+	"github.com/apex/log"
+)
+
+const (
+	defaultAddress         = ":8113"
+	defaultShutdownTimeout = 30 * time.Second
+	defaultReloadInterval  = 10 * time.Second
+	defaultRetention       = 30 * 24 * time.Hour
+	pruneInterval          = time.Hour
+)
+
+// ErrChecksFailed is returned by RunOnce if any healthcheck failed
+var ErrChecksFailed = errors.New("healthchecks failed")
+
+// Runtime manages the lifecycle of a kubecheck HTTP server
+type Runtime struct {
+	Server *http.Server
+	// StatusServer serves the status page if it has its own address
+	StatusServer *http.Server
+	statusPage   atomic.Value
+	kubecheck    atomic.Value
+	monitor      *monitor
+	runs         *runManager
+	ready        int32
+	reloadMu     sync.Mutex
+	configFile   string
+	configHash   [sha256.Size]byte
+	interval     time.Duration
+	stop         chan struct{}
+	err          error
+}
+
+// NewRuntime creates a new runtime for kubecheck
+func NewRuntime(kubecheck *config.Kubecheck) *Runtime {
+	// An invalid configuration, e.g. a webhook template that does not render, is returned by Run
+	err := config.Validate(kubecheck)
+
+	// A history that cannot be opened is returned by Run as well, instead of running without the stored results.
+	// The in-memory store only keeps the router usable until then.
+	history, herr := openHistory(kubecheck.Config.History)
+	if herr != nil {
+		log.WithFields(log.Fields{
+			"service": "History",
+			"path":    kubecheck.Config.History.Path,
+		}).WithError(herr).Error("failed to open result history")
+		herr = fmt.Errorf("result history %s: %v", kubecheck.Config.History.Path, herr)
+		history = store.NewMemoryStore()
+		if err == nil {
+			err = herr
+		}
+	}
+
+	m := newMonitor(history, hook.NewDispatcher(kubecheck.Config.Delivery))
+	runs := newRunManager(m, kubecheck.Config, kubecheck.Healthchecks)
+
+	if kubecheck.Router == nil {
+		kubecheck.Router = newRouter(kubecheck, m, runs)
+	}
+
+	rt := &Runtime{
+		monitor: m,
+		runs:    runs,
+		ready:   1,
+		stop:    make(chan struct{}),
+		err:     err,
+	}
+	rt.kubecheck.Store(kubecheck)
+
+	address := kubecheck.Config.Server.Address
+	if address == "" {
+		address = defaultAddress
+	}
+
+	rt.Server = &http.Server{
+		Handler:      rt,
+		Addr:         address,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: config.WriteTimeout,
+	}
+
+	if sp := kubecheck.Config.StatusPage; sp.Enabled && sp.Address != "" {
+		rt.statusPage.Store(newStatusPageRouter(kubecheck, m, ""))
+		rt.StatusServer = &http.Server{
+			Handler:      loggingMiddleware(http.HandlerFunc(rt.serveStatusPage)),
+			Addr:         sp.Address,
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}
+	}
+
+	return rt
+}
+
+func (rt *Runtime) serveStatusPage(w http.ResponseWriter, r *http.Request) {
+	rt.statusPage.Load().(http.Handler).ServeHTTP(w, r)
+}
+
+// ServeHTTP serves the lifecycle endpoints and delegates everything else to the router
+func (rt *Runtime) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/livez":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	case "/readyz":
+		if atomic.LoadInt32(&rt.ready) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("shutting down"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	default:
+		rt.Kubecheck().Router.ServeHTTP(w, r)
+	}
+}
+
+// Kubecheck returns the active kubecheck context
+func (rt *Runtime) Kubecheck() *config.Kubecheck {
+	return rt.kubecheck.Load().(*config.Kubecheck)
+}
+
+// Reload validates the kubecheck context and atomically replaces the active healthchecks and routes.
+// The active context is kept if the new one is invalid. Server settings are only applied on restart.
+func (rt *Runtime) Reload(kubecheck *config.Kubecheck) error {
+	if err := config.Validate(kubecheck); err != nil {
+		return err
+	}
+
+	rt.reloadMu.Lock()
+	defer rt.reloadMu.Unlock()
+
+	if kubecheck.Router == nil {
+		kubecheck.Router = newRouter(kubecheck, rt.monitor, rt.runs)
+	}
+
+	rt.runs.update(kubecheck.Config, kubecheck.Healthchecks)
+	rt.monitor.hooks.Configure(kubecheck.Config.Delivery)
+	if rt.StatusServer != nil {
+		rt.statusPage.Store(newStatusPageRouter(kubecheck, rt.monitor, ""))
+	}
+	rt.kubecheck.Store(kubecheck)
+
+	log.WithFields(log.Fields{
+		"service":      "HTTP-Server",
+		"healthchecks": len(kubecheck.Healthchecks),
+	}).Info("reloaded configuration")
+
+	return nil
+}
+
+// WatchConfigFile makes the runtime reload its configuration from path when a SIGHUP is received
+// or when the content of the file changes, which is checked at the given interval (10 seconds if zero).
+// It must be called before Run.
+func (rt *Runtime) WatchConfigFile(path string, interval time.Duration) {
+	if interval == 0 {
+		interval = defaultReloadInterval
+	}
+
+	rt.configFile = path
+	rt.interval = interval
+
+	if data, err := ioutil.ReadFile(path); err == nil {
+		rt.configHash = sha256.Sum256(data)
+	}
+}
+
+// reloadConfigFile reloads the configuration file, optionally only if its content has changed
+func (rt *Runtime) reloadConfigFile(onlyIfChanged bool) {
+	l := log.WithFields(log.Fields{
+		"service": "HTTP-Server",
+		"file":    rt.configFile,
+	})
+
+	data, err := ioutil.ReadFile(rt.configFile)
+	if err != nil {
+		l.WithError(err).Error("failed to read configuration, keeping the active configuration")
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	if onlyIfChanged && hash == rt.configHash {
+		return
+	}
+	rt.configHash = hash
+
+	kubecheck, err := config.Load(data)
+	if err == nil {
+		err = rt.Reload(kubecheck)
+	}
+
+	if err != nil {
+		l.WithError(err).Error("invalid configuration, keeping the active configuration")
+	}
+}
+
+func (rt *Runtime) watchConfigFile(reloads chan os.Signal) {
+	ticker := time.NewTicker(rt.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rt.reloadConfigFile(true)
+		case <-reloads:
+			log.WithFields(log.Fields{
+				"service": "HTTP-Server",
+				"file":    rt.configFile,
+			}).Info("received SIGHUP, reloading configuration")
+			rt.reloadConfigFile(false)
+		case <-rt.stop:
+			return
+		}
+	}
+}
+
+func openHistory(c config.HistoryConfig) (store.Store, error) {
+	if c.Path == "" {
+		return store.NewMemoryStore(), nil
+	}
+
+	log.WithFields(log.Fields{
+		"service": "History",
+		"path":    c.Path,
+	}).Info("opening result history")
+
+	return store.OpenBoltStore(c.Path)
+}
+
+// pruneHistory periodically removes results older than the retention
+func (rt *Runtime) pruneHistory() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		retention := rt.Kubecheck().Config.History.Retention
+		if retention == 0 {
+			retention = defaultRetention
+		}
+
+		if err := rt.monitor.history.Prune(time.Now().Add(-retention)); err != nil {
+			log.WithField("service", "History").WithError(err).Warn("failed to prune result history")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-rt.stop:
+			return
+		}
+	}
+}
+
+// Run starts the HTTP server and blocks until it fails or a SIGTERM or SIGINT has been handled
+func (rt *Runtime) Run() error {
+	if rt.err != nil {
+		return rt.err
+	}
+
+	go rt.pruneHistory()
+
+	log.WithFields(log.Fields{
+		"service": "HTTP-Server",
+		"address": rt.Server.Addr,
+	}).Infof("listening on %s", rt.Server.Addr)
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- rt.Server.ListenAndServe()
+	}()
+	if rt.StatusServer != nil {
+		log.WithFields(log.Fields{
+			"service": "HTTP-Server",
+			"address": rt.StatusServer.Addr,
+		}).Infof("serving status page on %s", rt.StatusServer.Addr)
+
+		go func() {
+			errs <- rt.StatusServer.ListenAndServe()
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+
+	if rt.configFile != "" {
+		reloads := make(chan os.Signal, 1)
+		signal.Notify(reloads, syscall.SIGHUP)
+		defer signal.Stop(reloads)
+		go rt.watchConfigFile(reloads)
+	}
+
+	select {
+	case err := <-errs:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	case sig := <-signals:
+		log.WithFields(log.Fields{
+			"service": "HTTP-Server",
+			"signal":  sig.String(),
+		}).Info("received signal, shutting down")
+	}
+
+	timeout := rt.Kubecheck().Config.Server.ShutdownTimeout
+	if timeout == 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return rt.Shutdown(ctx)
+}
+
+// RunOnce executes all healthchecks once without starting the HTTP server, delivers the webhooks, notifications and
+// metrics of the run and closes the result history, e.g. to run kubecheck as a Kubernetes CronJob.
+// It returns ErrChecksFailed if any check failed, and an error if the configuration is invalid or deliveries did not finish.
+func (rt *Runtime) RunOnce() error {
+	if rt.err != nil {
+		return rt.err
+	}
+
+	kubecheck := rt.Kubecheck()
+	failed := false
+	rt.monitor.runHealtchecks(context.Background(), kubecheck.Config, kubecheck.Healthchecks, runOptions{}, func(d checks.Description, r checks.Result) interface{} {
+		if r.Status == checks.Failed {
+			failed = true
+		}
+		return nil
+	})
+
+	timeout := kubecheck.Config.Server.ShutdownTimeout
+	if timeout == 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	l := log.WithFields(log.Fields{
+		"service": "Checks",
+	})
+
+	// Closing the dispatcher sends grouped changes and waits for queued deliveries, including their retries
+	herr := rt.monitor.hooks.Close(ctx)
+	if herr != nil {
+		l.WithError(herr).Warn("failed to deliver queued webhooks")
+	}
+
+	// The history is closed in any case, so the results of the run are flushed to disk
+	if err := rt.monitor.history.Close(); err != nil {
+		l.WithError(err).Warn("failed to close result history")
+	}
+
+	if herr != nil {
+		return herr
+	}
+	if failed {
+		return ErrChecksFailed
+	}
+	return nil
+}
+
+// Shutdown marks the runtime as not ready, stops accepting requests and drains in-flight healthcheck runs.
+// Runs that have not finished when the context is done are cancelled.
+func (rt *Runtime) Shutdown(ctx context.Context) error {
+	l := log.WithFields(log.Fields{
+		"service": "HTTP-Server",
+	})
+
+	if atomic.SwapInt32(&rt.ready, 0) == 1 {
+		close(rt.stop)
+	}
+
+	if delay := rt.Kubecheck().Config.Server.ShutdownDelay; delay > 0 {
+		l.Infof("waiting %v before closing listeners", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	if err := rt.Server.Shutdown(ctx); err != nil {
+		l.WithError(err).Warn("failed to drain HTTP requests")
+	}
+	if rt.StatusServer != nil {
+		if err := rt.StatusServer.Shutdown(ctx); err != nil {
+			l.WithError(err).Warn("failed to drain status page requests")
+		}
+	}
+
+	if err := rt.monitor.wait(ctx); err != nil {
+		l.WithError(err).Warn("failed to drain healthcheck runs, cancelling")
+		rt.runs.cancelAll()
+		rt.monitor.hooks.Close(ctx)
+		return err
+	}
+
+	if err := rt.monitor.hooks.Close(ctx); err != nil {
+		l.WithError(err).Warn("failed to deliver queued webhooks, cancelling")
+		return err
+	}
+
+	if err := rt.monitor.history.Close(); err != nil {
+		l.WithError(err).Warn("failed to close result history")
+	}
+
+	l.Info("shutdown completed")
+	return nil
+}
@@ -1,11 +1,11 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/StenaIT/kubecheck/checks"
 	"github.com/StenaIT/kubecheck/config"
@@ -16,50 +16,88 @@ import (
 
 type indexResponse struct {
 	Checks []checkDescriptionResponse `json:"checks"`
+	Groups []groupDescriptionResponse `json:"groups,omitempty"`
+}
+
+type groupDescriptionResponse struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+	Checks      []string `json:"checks"`
 }
 
 type checkDescriptionResponse struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	URL         string `json:"url"`
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	URL          string             `json:"url"`
+	Enabled      bool               `json:"enabled"`
+	Disabled     *disabledCheck     `json:"disabled,omitempty"`
+	Acknowledged *acknowledgedCheck `json:"acknowledged,omitempty"`
 }
 
 type apiCheckResponse struct {
-	Description string      `json:"description"`
-	Status      string      `json:"status"`
-	Reason      string      `json:"reason,omitempty"`
-	Input       interface{} `json:"input,omitempty"`
-	Output      interface{} `json:"output,omitempty"`
+	Description  string             `json:"description"`
+	Status       string             `json:"status"`
+	Reason       string             `json:"reason,omitempty"`
+	Acknowledged *acknowledgedCheck `json:"acknowledged,omitempty"`
+	Input        interface{}        `json:"input,omitempty"`
+	Output       interface{}        `json:"output,omitempty"`
+}
+
+type apiErrorResponse struct {
+	Error string `json:"error"`
 }
 
-// New creates a new HTTP server for kubecheck
+// New creates a new HTTP server for kubecheck. An invalid configuration or a history that cannot be opened is logged,
+// since the server is returned without the runtime that would report it.
+//
+// Deprecated: Use NewRuntime, whose Run returns these errors.
 func New(kubecheck *config.Kubecheck) *http.Server {
-	if kubecheck.Router == nil {
-		kubecheck.Router = mux.NewRouter()
-		kubecheck.Router.HandleFunc("/", indexHandler(kubecheck))
-		kubecheck.Router.HandleFunc("/checks/", healthchecksHandler(kubecheck.Config, kubecheck.Healthchecks))
+	rt := NewRuntime(kubecheck)
+	if rt.err != nil {
+		log.WithFields(log.Fields{
+			"service": "HTTP-Server",
+		}).WithError(rt.err).Error("invalid runtime")
+	}
+	return rt.Server
+}
 
-		for _, c := range kubecheck.Healthchecks {
-			hcks := []checks.Healthcheck{c}
-			kubecheck.Router.HandleFunc(getHealthcheckPath(c), healthchecksHandler(kubecheck.Config, hcks))
-		}
+func newRouter(kubecheck *config.Kubecheck, m *monitor, runs *runManager) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/", indexHandler(kubecheck, m))
+	router.HandleFunc("/checks/", healthchecksHandler(m, kubecheck.Config, kubecheck.Healthchecks, runOptions{}))
+	router.HandleFunc(getAllBadgePath(), badgeHandler(m, kubecheck.Config, kubecheck.Healthchecks, "kubecheck"))
+	router.HandleFunc("/reports", reportsHandler(kubecheck, m))
+	router.HandleFunc("/runs", createRunHandler(runs)).Methods("POST")
+	router.HandleFunc("/runs/{id}", getRunHandler(runs)).Methods("GET")
+	router.HandleFunc("/runs/{id}", cancelRunHandler(runs)).Methods("DELETE")
+
+	for _, c := range kubecheck.Healthchecks {
+		hcks := []checks.Healthcheck{c}
+		router.HandleFunc(getHealthcheckPath(c), healthchecksHandler(m, kubecheck.Config, hcks, runOptions{partial: partialRun(kubecheck.Healthchecks, hcks)}))
+		router.HandleFunc(getBadgePath(c), badgeHandler(m, kubecheck.Config, hcks, c.Describe().Name))
+	}
+
+	if len(kubecheck.Config.Federation.Peers) > 0 {
+		router.HandleFunc("/federation/", federationHandler(m, kubecheck.Config))
+	}
 
-		kubecheck.Router.Use(loggingMiddleware)
+	for _, g := range kubecheck.Groups {
+		gc := kubecheck.Config.ForGroup(g)
+		hcks := kubecheck.HealthchecksOf(g)
+		router.HandleFunc(getGroupPath(g), healthchecksHandler(m, gc, hcks, runOptions{scope: g.Name}))
+		router.HandleFunc(getGroupBadgePath(g), badgeHandler(m, gc, hcks, g.Name))
 	}
 
-	srv := &http.Server{
-		Handler:      kubecheck.Router,
-		Addr:         ":8113",
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+	if sp := kubecheck.Config.StatusPage; sp.Enabled && sp.Address == "" {
+		router.PathPrefix("/status/").Handler(newStatusPageRouter(kubecheck, m, "/status"))
 	}
 
-	log.WithFields(log.Fields{
-		"service": "HTTP-Server",
-		"address": srv.Addr,
-	}).Infof("listening on %s", srv.Addr)
+	registerAdminRoutes(router, kubecheck, m)
 
-	return srv
+	router.Use(loggingMiddleware)
+
+	return router
 }
 
 func loggingMiddleware(next http.Handler) http.Handler {
@@ -92,7 +130,7 @@ func (lrw *statusCodeResponseWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
-func indexHandler(kubecheck *config.Kubecheck) func(w http.ResponseWriter, r *http.Request) {
+func indexHandler(kubecheck *config.Kubecheck, m *monitor) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		statusCode := http.StatusOK
 		response := indexResponse{
@@ -101,75 +139,120 @@ func indexHandler(kubecheck *config.Kubecheck) func(w http.ResponseWriter, r *ht
 
 		for _, c := range kubecheck.Healthchecks {
 			d := c.Describe()
-			response.Checks = append(response.Checks, checkDescriptionResponse{
+			cdr := checkDescriptionResponse{
 				Name:        d.Name,
 				Description: d.Description,
 				URL:         generateURL(r, c),
-			})
-		}
+				Enabled:     true,
+			}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(statusCode)
+			if dc, ok := m.states.get(d.Name); ok {
+				cdr.Enabled = false
+				cdr.Disabled = &dc
+			}
+			cdr.Acknowledged = m.states.acknowledgement(d.Name)
 
-		js, err := json.Marshal(response)
-		if err == nil {
-			w.Write(js)
+			response.Checks = append(response.Checks, cdr)
 		}
+
+		for _, g := range kubecheck.Groups {
+			response.Groups = append(response.Groups, groupDescriptionResponse{
+				Name:        g.Name,
+				Description: g.Description,
+				URL:         generateBaseURL(r) + getGroupPath(g),
+				Checks:      g.Checks,
+			})
+		}
+
+		writeJSON(w, statusCode, response)
 	}
 }
 
-func healthchecksHandler(config *config.KubecheckConfig, healthchecks []checks.Healthcheck) func(w http.ResponseWriter, r *http.Request) {
+func healthchecksHandler(m *monitor, config *config.KubecheckConfig, healthchecks []checks.Healthcheck, opts runOptions) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// Unacceptable formats are rejected before running the checks and triggering the hooks
+		format, err := negotiateFormat(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
 		statusCode := http.StatusOK
 
-		results := runHealtchecks(config, healthchecks, func(d checks.Description, r checks.Result) interface{} {
+		results := m.runHealtchecks(context.Background(), config, healthchecks, opts, func(d checks.Description, r checks.Result) interface{} {
 			if r.Status == checks.Failed {
-				statusCode = http.StatusFailedDependency
+				statusCode = failedStatusCode(config)
 			}
 
-			var input interface{}
-			var output interface{}
-
-			if r.Status == checks.Failed || config.Debug {
-				input = r.Input
-				output = r.Output
-			}
-
-			return apiCheckResponse{
-				Description: d.Description,
-				Status:      r.Status,
-				Reason:      r.Reason,
-				Input:       input,
-				Output:      output,
-			}
+			return newAPICheckResponse(config, m, d, r)
 		})
 
 		if config.API.ForceOKStatusCode {
 			statusCode = http.StatusOK
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(statusCode)
+		writeResults(w, format, statusCode, results)
+	}
+}
 
-		js, err := json.Marshal(results)
-		if err == nil {
-			w.Write(js)
-		}
+func failedStatusCode(config *config.KubecheckConfig) int {
+	if config.API.FailedStatusCode != 0 {
+		return config.API.FailedStatusCode
 	}
+	return http.StatusFailedDependency
+}
+
+func newAPICheckResponse(config *config.KubecheckConfig, m *monitor, d checks.Description, r checks.Result) apiCheckResponse {
+	var input interface{}
+	var output interface{}
+
+	if r.Status == checks.Failed || config.Debug {
+		input = r.Input
+		output = r.Output
+	}
+
+	return apiCheckResponse{
+		Description:  d.Description,
+		Status:       r.Status,
+		Reason:       r.Reason,
+		Acknowledged: m.states.acknowledgement(d.Name),
+		Input:        input,
+		Output:       output,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	js, err := json.Marshal(response)
+	if err == nil {
+		w.Write(js)
+	}
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, apiErrorResponse{Error: err.Error()})
 }
 
 func generateURL(r *http.Request, healthcheck checks.Healthcheck) string {
-	path := getHealthcheckPath(healthcheck)
+	return generateBaseURL(r) + getHealthcheckPath(healthcheck)
+}
 
+func generateBaseURL(r *http.Request) string {
 	scheme := r.Header.Get("X-Forwarded-Proto")
 	if scheme == "" {
 		scheme = "http"
 	}
 
-	return fmt.Sprintf("%s://%s%s", scheme, r.Host, path)
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
 }
 
 func getHealthcheckPath(healthcheck checks.Healthcheck) string {
 	name := healthcheck.Describe().Name
 	return fmt.Sprintf("/checks/%s", url.PathEscape(name))
 }
+
+func getGroupPath(g config.Group) string {
+	return fmt.Sprintf("/groups/%s/", url.PathEscape(g.Name))
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+)
+
+func TestNewLogsInvalidRuntime(t *testing.T) {
+	logger := log.Log.(*log.Logger)
+	handler := logger.Handler
+	defer func() { logger.Handler = handler }()
+	entries := memory.New()
+	logger.Handler = entries
+
+	New(&config.Kubecheck{
+		Config: &config.KubecheckConfig{},
+		Healthchecks: []checks.Healthcheck{
+			staticCheck{name: "api", status: checks.Passed},
+			staticCheck{name: "api", status: checks.Passed},
+		},
+	})
+
+	for _, e := range entries.Entries {
+		if e.Level == log.ErrorLevel && e.Fields["error"] != nil {
+			return
+		}
+	}
+	t.Errorf("New() did not log the invalid configuration, got %d entries", len(entries.Entries))
+}
+
+func TestRunLogsTypeOfWrappedChecks(t *testing.T) {
+	logger := log.Log.(*log.Logger)
+	handler := logger.Handler
+	defer func() { logger.Handler = handler }()
+	entries := memory.New()
+	logger.Handler = entries
+
+	cfg := &config.KubecheckConfig{}
+	wrapped := checks.WithMetadata(staticCheck{name: "dns", status: checks.Failed}, checks.Metadata{Severity: "critical"})
+	runScope(newTestMonitor(), cfg, "", wrapped)
+
+	for _, e := range entries.Entries {
+		if e.Message == "finished executing healthcheck" {
+			if e.Fields["type"] != "staticCheck" {
+				t.Errorf("type = %v, want the type of the wrapped healthcheck", e.Fields["type"])
+			}
+			return
+		}
+	}
+	t.Errorf("the run did not log the healthcheck, got %d entries", len(entries.Entries))
+}
@@ -0,0 +1,449 @@
+package server
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	"github.com/StenaIT/kubecheck/config"
+	"github.com/StenaIT/kubecheck/store"
+
+	"github.com/apex/log"
+	"github.com/gorilla/mux"
+)
+
+const defaultStatusPageWindow = 7 * 24 * time.Hour
+
+// Component states
+const (
+	componentOperational   = "operational"
+	componentPartialOutage = "partial_outage"
+	componentMajorOutage   = "major_outage"
+	componentUnknown       = "unknown"
+)
+
+type apiStatusPageResponse struct {
+	Title      string                 `json:"title"`
+	Status     string                 `json:"status"`
+	Components []apiComponentResponse `json:"components"`
+	Incidents  []apiIncidentResponse  `json:"incidents"`
+}
+
+type apiComponentResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status"`
+}
+
+type apiIncidentResponse struct {
+	ID        string       `json:"id"`
+	Component string       `json:"component"`
+	Start     time.Time    `json:"start"`
+	End       *time.Time   `json:"end,omitempty"`
+	Resolved  bool         `json:"resolved"`
+	Notes     []store.Note `json:"notes"`
+}
+
+type addNoteRequest struct {
+	Author  string `json:"author"`
+	Message string `json:"message"`
+}
+
+// statusPage derives component states and incidents for customers, without exposing check details
+type statusPage struct {
+	config  config.StatusPageConfig
+	monitor *monitor
+}
+
+func newStatusPageRouter(kubecheck *config.Kubecheck, m *monitor, prefix string) *mux.Router {
+	sp := &statusPage{
+		config:  kubecheck.Config.StatusPage,
+		monitor: m,
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(prefix+"/", sp.htmlHandler).Methods("GET")
+	router.HandleFunc(prefix+"/api", sp.apiHandler).Methods("GET")
+	router.HandleFunc(prefix+"/feed.atom", sp.feedHandler).Methods("GET")
+
+	if token := kubecheck.Config.Admin.Token; token != "" {
+		notes := router.Path(prefix + "/incidents/{id}/notes").Subrouter()
+		notes.Use(adminAuthMiddleware(token))
+		notes.Methods("POST").HandlerFunc(sp.addNoteHandler)
+	}
+
+	return router
+}
+
+func (sp *statusPage) title() string {
+	if sp.config.Title != "" {
+		return sp.config.Title
+	}
+	return "Status"
+}
+
+func (sp *statusPage) window() time.Duration {
+	if sp.config.Window != 0 {
+		return sp.config.Window
+	}
+	return defaultStatusPageWindow
+}
+
+// componentStatus derives the status of a component from the latest results of its checks
+func (sp *statusPage) componentStatus(c config.Component) string {
+	known, failed := 0, 0
+	for _, name := range c.Checks {
+		if _, disabled := sp.monitor.states.get(name); disabled {
+			continue
+		}
+		status, ok := sp.latestStatus(name)
+		if !ok {
+			continue
+		}
+		known++
+		if status == checks.Failed {
+			failed++
+		}
+	}
+
+	switch {
+	case known == 0:
+		return componentUnknown
+	case failed == 0:
+		return componentOperational
+	case failed == known:
+		return componentMajorOutage
+	default:
+		return componentPartialOutage
+	}
+}
+
+// latestStatus returns the status of the latest result of a healthcheck within the window,
+// falling back to the history when no result is cached, e.g. after a restart
+func (sp *statusPage) latestStatus(name string) (string, bool) {
+	if cr, ok := sp.monitor.results.get(name, sp.window()); ok {
+		return cr.Result.Status, true
+	}
+
+	to := time.Now()
+	records, err := sp.monitor.history.Query(name, to.Add(-sp.window()), to)
+	if err != nil || len(records) == 0 {
+		return "", false
+	}
+	return records[len(records)-1].Status, true
+}
+
+// incidents derives incidents for each component from the failure periods of its checks, newest first
+func (sp *statusPage) incidents() ([]apiIncidentResponse, error) {
+	to := time.Now()
+	from := to.Add(-sp.window())
+	out := make([]apiIncidentResponse, 0)
+
+	for _, c := range sp.config.Components {
+		for _, name := range c.Checks {
+			records, err := sp.monitor.history.Query(name, from, to)
+			if err != nil {
+				return nil, err
+			}
+
+			incidents := store.Incidents(records)
+			if len(incidents) > 0 && failingAtStart(records) {
+				start, err := sp.streakStart(name, incidents[0].Start)
+				if err != nil {
+					return nil, err
+				}
+				incidents[0].Start = start
+			}
+
+			for _, incident := range incidents {
+				id := incidentID(c.Name, incident)
+				notes, err := sp.monitor.history.Notes(id)
+				if err != nil {
+					return nil, err
+				}
+
+				out = append(out, apiIncidentResponse{
+					ID:        id,
+					Component: c.Name,
+					Start:     incident.Start,
+					End:       incident.End,
+					Resolved:  incident.End != nil,
+					Notes:     notes,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Start.After(out[j].Start)
+	})
+
+	return out, nil
+}
+
+func (sp *statusPage) response() (apiStatusPageResponse, error) {
+	response := apiStatusPageResponse{
+		Title:      sp.title(),
+		Status:     componentOperational,
+		Components: make([]apiComponentResponse, 0),
+	}
+
+	for _, c := range sp.config.Components {
+		status := sp.componentStatus(c)
+		if status == componentMajorOutage || status == componentPartialOutage {
+			response.Status = componentPartialOutage
+		}
+		response.Components = append(response.Components, apiComponentResponse{
+			Name:        c.Name,
+			Description: c.Description,
+			Status:      status,
+		})
+	}
+
+	incidents, err := sp.incidents()
+	response.Incidents = incidents
+	return response, err
+}
+
+// failingAtStart returns whether a check was failing at the start of its records, before any of them passed
+func failingAtStart(records []store.Record) bool {
+	for _, r := range records {
+		switch r.Status {
+		case checks.Failed:
+			return true
+		case checks.Passed:
+			return false
+		}
+	}
+	return false
+}
+
+// streakStart returns when a check that was failing at the start of the window started failing, looking back one window at a time,
+// so an incident keeps its start and id while the window moves
+func (sp *statusPage) streakStart(name string, start time.Time) (time.Time, error) {
+	to := start
+	for {
+		from := to.Add(-sp.window())
+		records, err := sp.monitor.history.Query(name, from, to)
+		if err != nil {
+			return start, err
+		}
+		if len(records) == 0 {
+			return start, nil
+		}
+
+		for i := len(records) - 1; i >= 0; i-- {
+			switch records[i].Status {
+			case checks.Passed:
+				return start, nil
+			case checks.Failed:
+				start = records[i].Time
+			}
+		}
+		to = from
+	}
+}
+
+// incidentID returns a stable identifier for an incident of a component, based on when its check started failing
+func incidentID(component string, incident store.Incident) string {
+	h := sha1.Sum([]byte(fmt.Sprintf("%s/%s/%d", component, incident.Check, incident.Start.UnixNano())))
+	return hex.EncodeToString(h[:6])
+}
+
+func (sp *statusPage) apiHandler(w http.ResponseWriter, r *http.Request) {
+	response, err := sp.response()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (sp *statusPage) addNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	incidents, err := sp.incidents()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	found := false
+	for _, incident := range incidents {
+		if incident.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("incident not found"))
+		return
+	}
+
+	request := addNoteRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if request.Message == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("a message is required"))
+		return
+	}
+
+	note := store.Note{
+		Author:  request.Author,
+		Message: request.Message,
+		Time:    time.Now(),
+	}
+	if err := sp.monitor.history.AddNote(id, note); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"service":  "StatusPage",
+		"incident": id,
+		"author":   note.Author,
+	}).Info("added incident note")
+
+	writeJSON(w, http.StatusCreated, note)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string   `xml:"title"`
+	ID        string   `xml:"id"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Link      atomLink `xml:"link"`
+	Content   string   `xml:"content"`
+}
+
+func (sp *statusPage) feedHandler(w http.ResponseWriter, r *http.Request) {
+	incidents, err := sp.incidents()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	page := generateBaseURL(r) + strings.TrimSuffix(r.URL.Path, "feed.atom")
+	feed := atomFeed{
+		Title:   sp.title() + " - Incidents",
+		ID:      page,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: page},
+		Entries: make([]atomEntry, 0),
+	}
+
+	for _, incident := range incidents {
+		updated := incident.Start
+		if incident.End != nil {
+			updated = *incident.End
+		}
+
+		lines := []string{fmt.Sprintf("%s has been degraded since %s.", incident.Component, incident.Start.UTC().Format(time.RFC1123))}
+		if incident.End != nil {
+			lines = append(lines, fmt.Sprintf("Resolved at %s.", incident.End.UTC().Format(time.RFC1123)))
+		}
+		for _, n := range incident.Notes {
+			if n.Time.After(updated) {
+				updated = n.Time
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s", n.Time.UTC().Format(time.RFC1123), n.Message))
+		}
+
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     incidentTitle(incident),
+			ID:        "urn:kubecheck:incident:" + incident.ID,
+			Published: incident.Start.UTC().Format(time.RFC3339),
+			Updated:   updated.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: page + "#" + incident.ID},
+			Content:   strings.Join(lines, "\n"),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(feed)
+}
+
+func incidentTitle(incident apiIncidentResponse) string {
+	if incident.Resolved {
+		return fmt.Sprintf("Resolved: %s degraded", incident.Component)
+	}
+	return fmt.Sprintf("Ongoing: %s degraded", incident.Component)
+}
+
+var statusPageTemplate = template.Must(template.New("statuspage").Funcs(template.FuncMap{
+	"title": incidentTitle,
+	"time": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+	"label": func(status string) string {
+		return strings.Replace(status, "_", " ", -1)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="alternate" type="application/atom+xml" title="Incidents" href="feed.atom">
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; color: #333; }
+.component { display: flex; justify-content: space-between; padding: .75em; border-bottom: 1px solid #eee; }
+.operational { color: #2e7d32; } .partial_outage { color: #ef6c00; } .major_outage { color: #c62828; } .unknown { color: #9e9e9e; }
+.incident { margin-bottom: 1.5em; } .note { margin-left: 1em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<h2 class="{{.Status}}">{{if eq .Status "operational"}}All systems operational{{else}}Some systems are degraded{{end}}</h2>
+{{range .Components}}<div class="component"><span title="{{.Description}}">{{.Name}}</span><span class="{{.Status}}">{{label .Status}}</span></div>
+{{end}}
+<h2>Incidents</h2>
+{{range .Incidents}}<div class="incident" id="{{.ID}}">
+<h3>{{title .}}</h3>
+<div>Started {{time .Start}}{{if .End}}, resolved {{time .End}}{{end}}</div>
+{{range .Notes}}<p class="note"><strong>{{time .Time}}</strong> {{.Message}}</p>
+{{end}}</div>
+{{else}}<p>No incidents reported.</p>
+{{end}}
+<p><a href="feed.atom">Subscribe to incidents</a></p>
+</body>
+</html>
+`))
+
+func (sp *statusPage) htmlHandler(w http.ResponseWriter, r *http.Request) {
+	response, err := sp.response()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	statusPageTemplate.Execute(w, response)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+	conf "github.com/StenaIT/kubecheck/config"
+	"github.com/StenaIT/kubecheck/hook"
+)
+
+const defaultReminderInterval = time.Hour
+
+// transitionState is the last known status of a healthcheck
+type transitionState struct {
+	status       string
+	since        time.Time
+	lastNotified time.Time
+}
+
+// transitionKey identifies the status of a healthcheck as seen by the runs of a scope
+type transitionKey struct {
+	scope string
+	name  string
+}
+
+// checkTransitions detects changes in the status of healthchecks across runs.
+// The status is tracked per scope, the instance or a group, so the webhooks and notifiers of each scope are notified about every change,
+// whichever scope ran the check first.
+type checkTransitions struct {
+	mu     sync.Mutex
+	states map[transitionKey]*transitionState
+}
+
+func newCheckTransitions() *checkTransitions {
+	return &checkTransitions{
+		states: make(map[transitionKey]*transitionState),
+	}
+}
+
+// observe records the status of a healthcheck in a scope and returns the event to trigger, if any, and when the check started failing.
+// A check that passes on its first observation is not reported as recovered.
+func (t *checkTransitions) observe(scope string, name string, status string, now time.Time, reminder time.Duration) (hook.Event, time.Time) {
+	if status != checks.Passed && status != checks.Failed {
+		return "", time.Time{}
+	}
+
+	if reminder == 0 {
+		reminder = defaultReminderInterval
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := transitionKey{scope: scope, name: name}
+	s, ok := t.states[key]
+	if !ok {
+		s = &transitionState{status: checks.Passed, since: now}
+		t.states[key] = s
+	}
+
+	switch {
+	case status == checks.Failed && s.status != checks.Failed:
+		s.status, s.since, s.lastNotified = status, now, now
+		return conf.OnCheckFailedEvent, s.since
+	case status == checks.Failed && now.Sub(s.lastNotified) >= reminder:
+		s.lastNotified = now
+		return conf.OnCheckStillFailingEvent, s.since
+	case status == checks.Passed && s.status == checks.Failed:
+		failingSince := s.since
+		s.status, s.since = status, now
+		return conf.OnCheckRecoveredEvent, failingSince
+	}
+
+	return "", time.Time{}
+}
+
+// failingScopes returns the scopes in which the named healthcheck failed when it was last observed
+func (t *checkTransitions) failingScopes(name string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	scopes := make([]string, 0)
+	for key, s := range t.states {
+		if key.name == name && s.status == checks.Failed {
+			scopes = append(scopes, key.scope)
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	resultsBucket = []byte("results")
+	notesBucket   = []byte("notes")
+)
+
+// BoltStore keeps healthcheck results in an embedded on-disk database
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens or creates the database at path
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(resultsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(notesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Append stores a result
+func (s *BoltStore) Append(r Record) error {
+	value, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(resultsBucket).CreateBucketIfNotExists([]byte(r.Check))
+		if err != nil {
+			return err
+		}
+		return b.Put(timeKey(r.Time), value)
+	})
+}
+
+// Query returns the results of a healthcheck between from and to, ordered by time
+func (s *BoltStore) Query(check string, from time.Time, to time.Time) ([]Record, error) {
+	out := make([]Record, 0)
+	min, max := timeKey(from), timeKey(to)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(resultsBucket).ForEach(func(name []byte, _ []byte) error {
+			if check != "" && check != string(name) {
+				return nil
+			}
+
+			c := tx.Bucket(resultsBucket).Bucket(name).Cursor()
+			for k, v := c.Seek(min); k != nil && bytesLessOrEqual(k, max); k, v = c.Next() {
+				r := Record{}
+				if err := json.Unmarshal(v, &r); err != nil {
+					return err
+				}
+				out = append(out, r)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortRecords(out)
+	return out, nil
+}
+
+// Checks returns the names of all healthchecks with stored results
+func (s *BoltStore) Checks() ([]string, error) {
+	names := make([]string, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(resultsBucket).ForEach(func(name []byte, _ []byte) error {
+			names = append(names, string(name))
+			return nil
+		})
+	})
+	return names, err
+}
+
+// AddNote stores a note on an incident
+func (s *BoltStore) AddNote(incident string, n Note) error {
+	value, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(notesBucket).CreateBucketIfNotExists([]byte(incident))
+		if err != nil {
+			return err
+		}
+		return b.Put(timeKey(n.Time), value)
+	})
+}
+
+// Notes returns the notes of an incident, ordered by time
+func (s *BoltStore) Notes(incident string) ([]Note, error) {
+	notes := make([]Note, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(notesBucket).Bucket([]byte(incident))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_ []byte, v []byte) error {
+			n := Note{}
+			if err := json.Unmarshal(v, &n); err != nil {
+				return err
+			}
+			notes = append(notes, n)
+			return nil
+		})
+	})
+
+	return notes, err
+}
+
+// Prune removes all results and notes older than before
+func (s *BoltStore) Prune(before time.Time) error {
+	max := timeKey(before)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(resultsBucket).ForEach(func(name []byte, _ []byte) error {
+			c := tx.Bucket(resultsBucket).Bucket(name).Cursor()
+			for k, _ := c.First(); k != nil && !bytesLessOrEqual(max, k); k, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		expired := make([][]byte, 0)
+		err = tx.Bucket(notesBucket).ForEach(func(incident []byte, _ []byte) error {
+			k, _ := tx.Bucket(notesBucket).Bucket(incident).Cursor().Last()
+			if k == nil || !bytesLessOrEqual(max, k) {
+				expired = append(expired, append([]byte{}, incident...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, incident := range expired {
+			if err := tx.Bucket(notesBucket).DeleteBucket(incident); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close releases the resources of the store
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// timeKey encodes a time as a key that sorts chronologically
+func timeKey(t time.Time) []byte {
+	k := make([]byte, 8)
+	if t.After(time.Unix(0, 0)) {
+		binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	}
+	return k
+}
+
+func bytesLessOrEqual(a []byte, b []byte) bool {
+	return binary.BigEndian.Uint64(a) <= binary.BigEndian.Uint64(b)
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxMemoryRecords is the number of results kept in memory per healthcheck, about a week of results at one run per minute
+const maxMemoryRecords = 10000
+
+// MemoryStore keeps the latest healthcheck results in memory
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string][]Record
+	notes   map[string][]Note
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string][]Record),
+		notes:   make(map[string][]Note),
+	}
+}
+
+// Append stores a result, dropping the oldest result of the healthcheck once it has maxMemoryRecords
+func (s *MemoryStore) Append(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := append(s.records[r.Check], r)
+	if len(records) > maxMemoryRecords {
+		records = records[len(records)-maxMemoryRecords:]
+	}
+	s.records[r.Check] = records
+	return nil
+}
+
+// Query returns the results of a healthcheck between from and to, ordered by time
+func (s *MemoryStore) Query(check string, from time.Time, to time.Time) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Record, 0)
+	for name, records := range s.records {
+		if check != "" && check != name {
+			continue
+		}
+		for _, r := range records {
+			if !r.Time.Before(from) && !r.Time.After(to) {
+				out = append(out, r)
+			}
+		}
+	}
+
+	sortRecords(out)
+	return out, nil
+}
+
+// Checks returns the names of all healthchecks with stored results
+func (s *MemoryStore) Checks() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0)
+	for name := range s.records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// AddNote stores a note on an incident
+func (s *MemoryStore) AddNote(incident string, n Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notes[incident] = append(s.notes[incident], n)
+	return nil
+}
+
+// Notes returns the notes of an incident, ordered by time
+func (s *MemoryStore) Notes(incident string) ([]Note, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Note{}, s.notes[incident]...), nil
+}
+
+// Prune removes all results and notes older than before
+func (s *MemoryStore) Prune(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, records := range s.records {
+		i := sort.Search(len(records), func(i int) bool {
+			return !records[i].Time.Before(before)
+		})
+		if i == len(records) {
+			delete(s.records, name)
+			continue
+		}
+		s.records[name] = append([]Record(nil), records[i:]...)
+	}
+
+	for incident, notes := range s.notes {
+		if notes[len(notes)-1].Time.Before(before) {
+			delete(s.notes, incident)
+		}
+	}
+
+	return nil
+}
+
+// Close releases the resources of the store
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+// Incident defines a period in which a healthcheck was failing
+type Incident struct {
+	Check    string     `json:"check"`
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end,omitempty"`
+	Reason   string     `json:"reason"`
+	Failures int        `json:"failures"`
+}
+
+// Report defines availability statistics for a healthcheck over a window
+type Report struct {
+	Check   string    `json:"check"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Samples int       `json:"samples"`
+	// Uptime is nil if the check did not pass or fail within the window, since its uptime is unknown
+	Uptime      *float64      `json:"uptime,omitempty"`
+	Downtime    time.Duration `json:"downtime"`
+	Incidents   int           `json:"incidents"`
+	MTTR        time.Duration `json:"mttr"`
+	SLO         float64       `json:"slo"`
+	ErrorBudget ErrorBudget   `json:"errorBudget"`
+}
+
+// ErrorBudget defines how much of the allowed downtime of an SLO has been used
+type ErrorBudget struct {
+	Allowed   time.Duration `json:"allowed"`
+	Consumed  time.Duration `json:"consumed"`
+	Remaining time.Duration `json:"remaining"`
+	// Burn is the consumed share of the budget, where 1 means the budget is exhausted
+	Burn float64 `json:"burn"`
+}
+
+// Incidents derives incidents from the results of a single healthcheck, ordered by time.
+// An incident starts with a failed result and ends with the next passed result.
+func Incidents(records []Record) []Incident {
+	incidents := make([]Incident, 0)
+	var current *Incident
+
+	for _, r := range records {
+		switch r.Status {
+		case checks.Failed:
+			if current == nil {
+				current = &Incident{Check: r.Check, Start: r.Time, Reason: r.Reason}
+			}
+			current.Failures++
+		case checks.Passed:
+			if current != nil {
+				end := r.Time
+				current.End = &end
+				incidents = append(incidents, *current)
+				current = nil
+			}
+		}
+	}
+
+	if current != nil {
+		incidents = append(incidents, *current)
+	}
+
+	return incidents
+}
+
+// NewReport computes availability statistics from the results of a single healthcheck between from and to.
+// Each result is assumed to hold until the next one, and time before the first result is not counted.
+func NewReport(check string, records []Record, from time.Time, to time.Time, slo float64) Report {
+	report := Report{
+		Check:   check,
+		From:    from,
+		To:      to,
+		Samples: len(records),
+		SLO:     slo,
+	}
+
+	var up, down time.Duration
+	for i, r := range records {
+		end := to
+		if i+1 < len(records) {
+			end = records[i+1].Time
+		}
+
+		d := end.Sub(r.Time)
+		switch r.Status {
+		case checks.Failed:
+			down += d
+		case checks.Passed:
+			up += d
+		}
+	}
+
+	if up+down > 0 {
+		uptime := float64(up) / float64(up+down) * 100
+		report.Uptime = &uptime
+	}
+	report.Downtime = down
+
+	incidents := Incidents(records)
+	report.Incidents = len(incidents)
+
+	var repairTime time.Duration
+	resolved := 0
+	for _, incident := range incidents {
+		if incident.End != nil {
+			repairTime += incident.End.Sub(incident.Start)
+			resolved++
+		}
+	}
+	if resolved > 0 {
+		report.MTTR = repairTime / time.Duration(resolved)
+	}
+
+	allowed := time.Duration(float64(to.Sub(from)) * (100 - slo) / 100)
+	report.ErrorBudget = ErrorBudget{
+		Allowed:   allowed,
+		Consumed:  down,
+		Remaining: allowed - down,
+	}
+	if allowed > 0 {
+		report.ErrorBudget.Burn = float64(down) / float64(allowed)
+	}
+
+	return report
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+var reportStart = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// record returns a result of dns the given time after reportStart
+func record(status string, after time.Duration) Record {
+	return Record{Check: "dns", Status: status, Reason: status, Time: reportStart.Add(after)}
+}
+
+func TestReportWithoutHistory(t *testing.T) {
+	to := reportStart.Add(4 * time.Hour)
+	report := NewReport("dns", nil, reportStart, to, 90)
+
+	if report.Samples != 0 || report.Uptime != nil || report.Downtime != 0 || report.Incidents != 0 || report.MTTR != 0 {
+		t.Errorf("report = %+v, want no samples and an unknown uptime", report)
+	}
+	want := ErrorBudget{Allowed: 24 * time.Minute, Remaining: 24 * time.Minute}
+	if report.ErrorBudget != want {
+		t.Errorf("error budget = %+v, want %+v", report.ErrorBudget, want)
+	}
+}
+
+func TestReportOpenFailureStreak(t *testing.T) {
+	records := []Record{
+		record(checks.Passed, 0),
+		record(checks.Failed, time.Hour),
+		record(checks.Failed, 2*time.Hour),
+	}
+	report := NewReport("dns", records, reportStart, reportStart.Add(4*time.Hour), 90)
+
+	if report.Uptime == nil || *report.Uptime != 25 {
+		t.Errorf("uptime = %v, want 25", report.Uptime)
+	}
+	if report.Downtime != 3*time.Hour || report.Incidents != 1 {
+		t.Errorf("downtime = %s, incidents = %d, want the failure to last until the end of the window", report.Downtime, report.Incidents)
+	}
+	if report.MTTR != 0 {
+		t.Errorf("MTTR = %s, want no repair time of an unresolved incident", report.MTTR)
+	}
+	if report.ErrorBudget.Consumed != 3*time.Hour || report.ErrorBudget.Remaining != -156*time.Minute || report.ErrorBudget.Burn != 7.5 {
+		t.Errorf("error budget = %+v, want it exhausted", report.ErrorBudget)
+	}
+
+	incidents := Incidents(records)
+	if len(incidents) != 1 || incidents[0].End != nil || incidents[0].Failures != 2 || !incidents[0].Start.Equal(records[1].Time) {
+		t.Errorf("incidents = %+v, want one open incident of 2 failures", incidents)
+	}
+}
+
+func TestReportResolvedIncidents(t *testing.T) {
+	records := []Record{
+		record(checks.Failed, 0),
+		record(checks.Passed, time.Hour),
+		record(checks.Disabled, 2*time.Hour),
+		record(checks.Failed, 3*time.Hour),
+		record(checks.Passed, 6*time.Hour),
+	}
+	report := NewReport("dns", records, reportStart, reportStart.Add(8*time.Hour), 90)
+
+	if report.Incidents != 2 || report.MTTR != 2*time.Hour {
+		t.Errorf("incidents = %d, MTTR = %s, want 2 incidents repaired in 2h on average", report.Incidents, report.MTTR)
+	}
+	// Time in which the check was disabled counts neither as up nor down
+	if report.Uptime == nil || *report.Uptime != 3.0/7*100 {
+		t.Errorf("uptime = %v, want 3h of 7h", report.Uptime)
+	}
+}
+
+func TestReportWindowBoundary(t *testing.T) {
+	s := NewMemoryStore()
+	for _, r := range []Record{
+		record(checks.Failed, -time.Minute),
+		record(checks.Passed, 0),
+		record(checks.Failed, time.Hour),
+		record(checks.Failed, 2*time.Hour),
+		record(checks.Passed, 2*time.Hour+time.Nanosecond),
+	} {
+		s.Append(r)
+	}
+
+	from, to := reportStart, reportStart.Add(2*time.Hour)
+	records, err := s.Query("dns", from, to)
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	// Results at the bounds of the window are included
+	if len(records) != 3 || !records[0].Time.Equal(from) || !records[2].Time.Equal(to) {
+		t.Fatalf("Query() = %+v, want the results from %s to %s", records, from, to)
+	}
+
+	// The result at the end of the window holds for no time
+	report := NewReport("dns", records, from, to, 90)
+	if report.Samples != 3 || report.Downtime != time.Hour || report.Uptime == nil || *report.Uptime != 50 {
+		t.Errorf("report = %+v, want an hour up and an hour down", report)
+	}
+	if report.Incidents != 1 || report.MTTR != 0 {
+		t.Errorf("incidents = %d, MTTR = %s, want the incident open at the end of the window", report.Incidents, report.MTTR)
+	}
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"sort"
+	"time"
+)
+
+// Record defines a stored healthcheck result
+type Record struct {
+	Check    string        `json:"check"`
+	Status   string        `json:"status"`
+	Reason   string        `json:"reason,omitempty"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Input    interface{}   `json:"input,omitempty"`
+	Output   interface{}   `json:"output,omitempty"`
+}
+
+// Note defines a note posted by an operator on an incident
+type Note struct {
+	Author  string    `json:"author"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Store defines a storage of healthcheck results
+type Store interface {
+	// Append stores a result
+	Append(r Record) error
+	// Query returns the results of a healthcheck between from and to, ordered by time.
+	// The results of all healthchecks are returned if check is empty.
+	Query(check string, from time.Time, to time.Time) ([]Record, error)
+	// Checks returns the names of all healthchecks with stored results
+	Checks() ([]string, error)
+	// AddNote stores a note on an incident
+	AddNote(incident string, n Note) error
+	// Notes returns the notes of an incident, ordered by time
+	Notes(incident string) ([]Note, error)
+	// Prune removes all results and notes older than before
+	Prune(before time.Time) error
+	// Close releases the resources of the store
+	Close() error
+}
+
+func sortRecords(records []Record) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/StenaIT/kubecheck/checks"
+)
+
+// openBoltStore opens a store in a temporary directory, which is removed by the returned func
+func openBoltStore(t *testing.T) (*BoltStore, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "kubecheck")
+	if err != nil {
+		t.Fatalf("creating a temporary directory: %v", err)
+	}
+	s, err := OpenBoltStore(filepath.Join(dir, "history.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("OpenBoltStore() error: %v", err)
+	}
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestMemoryStoreCap(t *testing.T) {
+	s := NewMemoryStore()
+	for i := 0; i < maxMemoryRecords+5; i++ {
+		s.Append(record(checks.Passed, time.Duration(i)*time.Minute))
+	}
+	s.Append(Record{Check: "api", Status: checks.Passed, Time: reportStart})
+
+	records, err := s.Query("dns", reportStart, reportStart.Add(time.Duration(maxMemoryRecords+5)*time.Minute))
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	if len(records) != maxMemoryRecords || !records[0].Time.Equal(reportStart.Add(5*time.Minute)) {
+		t.Errorf("got %d results from %s, want the latest %d", len(records), records[0].Time, maxMemoryRecords)
+	}
+
+	// The cap applies per healthcheck
+	if api, _ := s.Query("api", reportStart, reportStart); len(api) != 1 {
+		t.Errorf("got %d results of api, want 1", len(api))
+	}
+}
+
+func TestBoltStoreOrdering(t *testing.T) {
+	s, remove := openBoltStore(t)
+	defer remove()
+
+	// Results are appended out of order and across healthchecks
+	for _, r := range []Record{
+		record(checks.Failed, 3*time.Hour),
+		{Check: "api", Status: checks.Passed, Time: reportStart.Add(2 * time.Hour)},
+		record(checks.Passed, time.Hour),
+		record(checks.Failed, 0),
+	} {
+		if err := s.Append(r); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	records, err := s.Query("", reportStart, reportStart.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d results, want 4", len(records))
+	}
+	for i, want := range []time.Duration{0, time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		if !records[i].Time.Equal(reportStart.Add(want)) {
+			t.Errorf("result %d at %s, want %s", i, records[i].Time, reportStart.Add(want))
+		}
+	}
+
+	dns, err := s.Query("dns", reportStart.Add(time.Hour), reportStart.Add(3*time.Hour))
+	if err != nil || len(dns) != 2 || dns[0].Status != checks.Passed || dns[1].Status != checks.Failed {
+		t.Errorf("Query(dns) = %+v, %v, want the results within the window", dns, err)
+	}
+
+	names, err := s.Checks()
+	if err != nil || len(names) != 2 || names[0] != "api" || names[1] != "dns" {
+		t.Errorf("Checks() = %v, %v", names, err)
+	}
+}
+
+func TestBoltStorePrune(t *testing.T) {
+	s, remove := openBoltStore(t)
+	defer remove()
+
+	for _, after := range []time.Duration{0, time.Hour, 2 * time.Hour} {
+		s.Append(record(checks.Passed, after))
+	}
+	s.AddNote("dns", Note{Author: "jane", Message: "looking", Time: reportStart})
+
+	if err := s.Prune(reportStart.Add(time.Hour)); err != nil {
+		t.Fatalf("Prune() error: %v", err)
+	}
+	records, _ := s.Query("dns", reportStart, reportStart.Add(2*time.Hour))
+	if len(records) != 2 || !records[0].Time.Equal(reportStart.Add(time.Hour)) {
+		t.Errorf("results after Prune() = %+v, want the results from the hour on", records)
+	}
+	if notes, _ := s.Notes("dns"); len(notes) != 0 {
+		t.Errorf("notes after Prune() = %+v, want none", notes)
+	}
+}